@@ -3,6 +3,9 @@ package project_config
 type Dataset struct {
 	ID             string `toml:"id"`
 	LocalDirectory string `toml:"localDirectory"`
+
+	// Snapshot is the tag of the snapshot the dataset is pinned to, if any
+	Snapshot string `toml:"snapshot,omitempty"`
 }
 
 type Datasets map[string]Dataset
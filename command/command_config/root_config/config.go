@@ -8,6 +8,8 @@ type Config struct {
 	Auth Auth `toml:"auth"`
 
 	Workspace Workspace `toml:"workspace"`
+
+	Transfer Transfer `toml:"transfer"`
 }
 
 func SetDefaultConfig(v *viper.Viper) {
@@ -18,9 +20,14 @@ func SetDefaultConfig(v *viper.Viper) {
 	v.SetDefault("workspace", Workspace{
 		Username: "",
 	})
+	v.SetDefault("transfer", Transfer{
+		Concurrency:  0,
+		MaxBandwidth: "",
+	})
 }
 
 func (c *Config) WriteStructIntoViper(v *viper.Viper) {
 	v.Set("auth", c.Auth)
 	v.Set("workspace", c.Workspace)
+	v.Set("transfer", c.Transfer)
 }
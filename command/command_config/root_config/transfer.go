@@ -0,0 +1,10 @@
+package root_config
+
+// Transfer holds the settings of every dataset command that transfers files,
+// which the flags of "deploifai dataset push" and "pull" override.
+type Transfer struct {
+	// Concurrency is the number of files transferred at the same time, 0 means one per CPU.
+	Concurrency int `toml:"concurrency"`
+	// MaxBandwidth limits the bandwidth of all transfers together, e.g. "50MB/s", empty means unlimited.
+	MaxBandwidth string `toml:"maxBandwidth"`
+}
@@ -0,0 +1,183 @@
+/*
+Copyright © 2023 Sean Chok
+*/
+package dataset
+
+import (
+	"errors"
+	"fmt"
+	"github.com/deploifai/cli-go/command/ctx"
+	"github.com/deploifai/cli-go/command/dataset/data_storage"
+	"github.com/spf13/cobra"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var catRange string
+
+// byteRange is a range of the bytes of an object, Length is -1 to read to the end of the object.
+type byteRange struct {
+	Offset int64
+	Length int64
+}
+
+// catCmd represents the cat command
+var catCmd = &cobra.Command{
+	Use:   "cat <path>",
+	Short: "Print a file of a dataset to stdout",
+	Long: `Stream a file of a dataset to stdout, without downloading it to the local filesystem,
+e.g. "deploifai dataset cat labels.csv | head".
+
+<path> is resolved like the paths of "deploifai dataset pull", relative to the current directory,
+and does not have to exist locally.
+
+Use --range to print only some of the bytes of the file, as START-END with both ends included,
+START- to print from START to the end, or -N to print the last N bytes, e.g. --range 0-1023 or --range -4096.
+Bytes are counted from 0.
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		_context := ctx.GetContextValue(cmd)
+
+		// get the dataset and directory path from config
+		ok, ds, datasetDirPath, err := getTargetDataset(cmd)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("the current directory is not initialised as a dataset, use --dataset to choose one")
+		}
+
+		remoteObjectKey, err := getRemoteObjectKey(datasetDirPath, args[0])
+		if err != nil {
+			return err
+		}
+
+		client, err := data_storage.New(cmd.Context(), _context.ServiceClientConfig.API, ds.ID, nil)
+		if err != nil {
+			return err
+		}
+
+		remoteObject, err := getRemoteObject(client, remoteObjectKey)
+		if err != nil {
+			return err
+		}
+
+		r, err := parseByteRange(catRange, remoteObject.Size)
+		if err != nil {
+			return err
+		}
+		if r.Length == 0 {
+			return nil
+		}
+
+		reader, err := client.OpenObjectRange(remoteObject.Key, r.Offset, r.Length)
+		if err != nil {
+			return err
+		}
+		defer func(reader io.ReadCloser) {
+			_ = reader.Close()
+		}(reader)
+
+		_, err = io.Copy(cmd.OutOrStdout(), reader)
+
+		return err
+	},
+}
+
+func init() {
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// catCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// catCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	catCmd.Flags().StringVar(&catRange, "range", "", "bytes of the file to print, e.g. 0-1023, 1024- or -4096")
+}
+
+// getRemoteObjectKey resolves arg, a path that does not have to exist locally, to the key of a file in the dataset.
+func getRemoteObjectKey(datasetDirPath string, arg string) (string, error) {
+
+	absPaths, err := getAbsPaths(datasetDirPath, []string{arg})
+	if err != nil {
+		return "", err
+	}
+
+	if ok, _, err := verifyPullPaths(datasetDirPath, []string{arg}, absPaths); err != nil {
+		return "", err
+	} else if !ok {
+		return "", errors.New(fmt.Sprintf("invalid path: %s", arg))
+	}
+
+	remoteObjectPrefixes, err := getRemoteObjectPrefixes(datasetDirPath, absPaths)
+	if err != nil {
+		return "", err
+	}
+
+	key := filepath.ToSlash(remoteObjectPrefixes[0])
+	if key == "." {
+		return "", errors.New(fmt.Sprintf("%s is the root of the dataset, not a file", arg))
+	}
+
+	return key, nil
+}
+
+// parseByteRange parses a range of the bytes of an object of the given size, as START-END, START- or -N.
+// The range is cut short at the end of the object. An empty string is the whole object.
+func parseByteRange(s string, size int64) (byteRange, error) {
+
+	invalid := errors.New(fmt.Sprintf("invalid range: %s, use START-END, START- or -N, e.g. 0-1023", s))
+
+	if s == "" {
+		return byteRange{Offset: 0, Length: -1}, nil
+	}
+
+	start, end, found := strings.Cut(strings.TrimSpace(s), "-")
+	if !found || (start == "" && end == "") {
+		return byteRange{}, invalid
+	}
+
+	// -N is the last N bytes
+	if start == "" {
+		n, err := strconv.ParseInt(end, 10, 64)
+		if err != nil || n < 0 {
+			return byteRange{}, invalid
+		}
+		if n > size {
+			n = size
+		}
+		return byteRange{Offset: size - n, Length: n}, nil
+	}
+
+	offset, err := strconv.ParseInt(start, 10, 64)
+	if err != nil || offset < 0 {
+		return byteRange{}, invalid
+	}
+	if size == 0 {
+		return byteRange{Offset: 0, Length: 0}, nil
+	}
+	if offset >= size {
+		return byteRange{}, errors.New(fmt.Sprintf("invalid range: %s, the file has %d bytes", s, size))
+	}
+
+	if end == "" {
+		return byteRange{Offset: offset, Length: size - offset}, nil
+	}
+
+	last, err := strconv.ParseInt(end, 10, 64)
+	if err != nil || last < offset {
+		return byteRange{}, invalid
+	}
+	if last >= size {
+		last = size - 1
+	}
+
+	return byteRange{Offset: offset, Length: last - offset + 1}, nil
+}
@@ -0,0 +1,44 @@
+package dataset
+
+import "testing"
+
+func TestParseByteRange(t *testing.T) {
+	tests := []struct {
+		s       string
+		size    int64
+		want    byteRange
+		wantErr bool
+	}{
+		{s: "", size: 100, want: byteRange{Offset: 0, Length: -1}},
+		{s: "0-9", size: 100, want: byteRange{Offset: 0, Length: 10}},
+		{s: " 10-19 ", size: 100, want: byteRange{Offset: 10, Length: 10}},
+		{s: "99-99", size: 100, want: byteRange{Offset: 99, Length: 1}},
+		{s: "90-200", size: 100, want: byteRange{Offset: 90, Length: 10}},
+		{s: "90-", size: 100, want: byteRange{Offset: 90, Length: 10}},
+		{s: "0-", size: 100, want: byteRange{Offset: 0, Length: 100}},
+		{s: "-10", size: 100, want: byteRange{Offset: 90, Length: 10}},
+		{s: "-200", size: 100, want: byteRange{Offset: 0, Length: 100}},
+		{s: "-0", size: 100, want: byteRange{Offset: 100, Length: 0}},
+		{s: "0-9", size: 0, want: byteRange{Offset: 0, Length: 0}},
+		{s: "-10", size: 0, want: byteRange{Offset: 0, Length: 0}},
+		{s: "100-", size: 100, wantErr: true},
+		{s: "100-200", size: 100, wantErr: true},
+		{s: "20-10", size: 100, wantErr: true},
+		{s: "-", size: 100, wantErr: true},
+		{s: "10", size: 100, wantErr: true},
+		{s: "a-b", size: 100, wantErr: true},
+		{s: "0-b", size: 100, wantErr: true},
+		{s: "--5", size: 100, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseByteRange(tt.s, tt.size)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseByteRange(%q, %d) returned error %v, want error %v", tt.s, tt.size, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseByteRange(%q, %d) = %+v, want %+v", tt.s, tt.size, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+/*
+Copyright © 2023 Sean Chok
+*/
+package dataset
+
+import (
+	"errors"
+	"fmt"
+	"github.com/deploifai/cli-go/command/ctx"
+	"github.com/deploifai/cli-go/command/dataset/data_storage"
+	"github.com/spf13/cobra"
+	"sort"
+	"strings"
+)
+
+var checkoutYes bool
+var checkoutDryRun bool
+
+// checkoutCmd represents the checkout command
+var checkoutCmd = &cobra.Command{
+	Use:   "checkout <tag>",
+	Short: "Bring the local files of a dataset to a snapshot",
+	Long: `Bring the local files of a dataset to the state recorded in a snapshot, created with "deploifai dataset snapshot".
+
+Files that differ from the snapshot are downloaded as they were when the snapshot was created,
+and local files that are not in the snapshot are deleted, except for files ignored by .deploifaiignore files.
+The files to delete are listed and have to be confirmed, unless --yes is given.
+Use --dry-run to list the files that would be downloaded or deleted, without changing anything.
+
+The files in the dataset itself are not changed.
+`,
+	Args: cobra.ExactArgs(1), // requires exactly 1 arg, which is the tag of the snapshot
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		_context := ctx.GetContextValue(cmd)
+
+		// get the dataset and directory path from config
+		ok, ds, datasetDirPath, err := getTargetDataset(cmd)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("the current directory is not initialised as a dataset, use --dataset to choose one")
+		}
+
+		opts, err := newTransferOptions(_context.Root.Transfer, 0, "", false)
+		if err != nil {
+			return err
+		}
+
+		client, err := data_storage.New(cmd.Context(), _context.ServiceClientConfig.API, ds.ID, opts.Traffic)
+		if err != nil {
+			return err
+		}
+
+		return checkoutSnapshot(cmd, client, opts, datasetDirPath, args[0], checkoutYes, checkoutDryRun)
+	},
+}
+
+func init() {
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// checkoutCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// checkoutCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	checkoutCmd.Flags().BoolVarP(&checkoutYes, "yes", "y", false, "delete local files that are not in the snapshot without asking for confirmation")
+	checkoutCmd.Flags().BoolVar(&checkoutDryRun, "dry-run", false, "list the files that would be downloaded or deleted, without changing anything")
+}
+
+// checkoutSnapshot brings the local files in datasetDirPath to the snapshot with the given tag.
+func checkoutSnapshot(cmd *cobra.Command, client data_storage.Client, opts transferOptions, datasetDirPath string, tag string, yes bool, dryRun bool) error {
+
+	s, err := loadSnapshot(client, tag)
+	if err != nil {
+		return err
+	}
+	remoteObjects := s.remoteObjects()
+
+	if err = verifySnapshotObjects(client, remoteObjects); err != nil {
+		return err
+	}
+
+	datasetDirs := getDatasetDirs(*ctx.GetContextValue(cmd).Project)
+
+	m, _, err := loadManifest(datasetDirPath)
+	if err != nil {
+		cmd.Printf("Warning: %s, comparing files by size and modification time only\n", err)
+	}
+
+	objects, err := getObjectsToPull(m, datasetDirPath, datasetDirs, datasetDirPath, remoteObjects)
+	if err != nil {
+		return err
+	}
+
+	localObjects, err := listLocalObjects(newIgnoreMatcher(datasetDirPath), datasetDirPath, datasetDirs, datasetDirPath)
+	if err != nil {
+		return err
+	}
+	deletions := getLocalObjectsToDelete(localObjects, remoteObjects)
+
+	if dryRun {
+		planPull(datasetDirPath, remoteObjects, objects, deletions)
+		return nil
+	}
+
+	j, err := loadJournal(datasetDirPath)
+	if err != nil {
+		return err
+	}
+	j.replay(m)
+	if err = j.start("checkout", []string{""}); err != nil {
+		return err
+	}
+	defer j.close()
+
+	skipped := len(remoteObjects) - len(objects)
+
+	if len(objects) > 0 {
+		err = pullObjects(client, opts, m, j, datasetDirPath, objects, skipped, fmt.Sprintf("Checking out %s", tag))
+
+		// save the files that were pulled, even if some failed, so that they are not pulled again
+		if saveErr := m.save(); err == nil {
+			err = saveErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(deletions) > 0 {
+		var paths []string
+		for _, o := range deletions {
+			paths = append(paths, displayPath(o.AbsPath))
+		}
+
+		if confirmed, err := confirmDelete(paths, yes); err != nil {
+			return err
+		} else if !confirmed {
+			fmt.Println("Nothing was deleted, the local files are not exactly the snapshot")
+		} else if err = deleteLocalObjects(m, deletions, datasetDirPath); err != nil {
+			return err
+		} else if err = m.save(); err != nil {
+			return err
+		}
+	}
+
+	if err = j.finish(client); err != nil {
+		return err
+	}
+
+	cmd.Printf("Checked out snapshot %s, %d files were unchanged\n", tag, skipped)
+
+	return nil
+}
+
+// verifySnapshotObjects returns an error if an object of a snapshot has no version and has changed since the snapshot,
+// as it cannot be downloaded the way it was. It is called before anything is transferred.
+// An object is taken to have changed if its size, ETag or generation, when it has one, differs.
+func verifySnapshotObjects(client data_storage.Client, snapshotObjects map[string]data_storage.Object) error {
+
+	currentObjects, err := listRemoteObjects(client, "")
+	if err != nil {
+		return err
+	}
+
+	var changed []string
+	for key, o := range snapshotObjects {
+		if o.Version != "" {
+			continue
+		}
+		if current, ok := currentObjects[key]; !ok || current.Size != o.Size || current.ETag != o.ETag || (o.Generation != "" && current.Generation != o.Generation) {
+			changed = append(changed, key)
+		}
+	}
+
+	if len(changed) == 0 {
+		return nil
+	}
+
+	sort.Strings(changed)
+
+	return errors.New(fmt.Sprintf("the cloud storage does not keep versions of these files, which changed since the snapshot was created: %s", strings.Join(changed, ", ")))
+}
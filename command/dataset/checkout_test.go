@@ -0,0 +1,53 @@
+package dataset
+
+import (
+	"github.com/deploifai/cli-go/command/dataset/data_storage"
+	"testing"
+)
+
+// fakeGenerationClient is a fakeClient that numbers the writes of objects, as Google Cloud Storage does.
+type fakeGenerationClient struct {
+	*fakeClient
+	generations map[string]string
+}
+
+func (f *fakeGenerationClient) ListObjects(prefix string) ([]data_storage.Object, error) {
+
+	objects, err := f.fakeClient.ListObjects(prefix)
+	for i := range objects {
+		objects[i].Generation = f.generations[objects[i].Key]
+	}
+
+	return objects, err
+}
+
+func TestVerifySnapshotObjects(t *testing.T) {
+	eTag := `"` + testMD5("hello") + `"`
+
+	tests := []struct {
+		name    string
+		object  data_storage.Object
+		wantErr bool
+	}{
+		{name: "unchanged", object: data_storage.Object{Key: "a.txt", Size: 5, ETag: eTag, Generation: "1"}},
+		{name: "changed size", object: data_storage.Object{Key: "a.txt", Size: 4, ETag: eTag, Generation: "1"}, wantErr: true},
+		{name: "changed ETag", object: data_storage.Object{Key: "a.txt", Size: 5, ETag: `"other"`, Generation: "1"}, wantErr: true},
+		// an object rewritten with the same content has the same ETag, but a new generation
+		{name: "changed generation", object: data_storage.Object{Key: "a.txt", Size: 5, ETag: eTag, Generation: "0"}, wantErr: true},
+		{name: "recorded without a generation", object: data_storage.Object{Key: "a.txt", Size: 5, ETag: eTag}},
+		{name: "deleted", object: data_storage.Object{Key: "b.txt", Size: 5, ETag: eTag}, wantErr: true},
+		{name: "changed with a version", object: data_storage.Object{Key: "a.txt", Size: 4, ETag: `"other"`, Version: "0", Generation: "0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeGenerationClient{fakeClient: newFakeClient(), generations: map[string]string{"a.txt": "1"}}
+			client.objects["a.txt"] = []byte("hello")
+
+			err := verifySnapshotObjects(client, map[string]data_storage.Object{tt.object.Key: tt.object})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("verifySnapshotObjects() returned error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
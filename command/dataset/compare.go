@@ -0,0 +1,281 @@
+package dataset
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/deploifai/cli-go/command/dataset/data_storage"
+	"github.com/deploifai/sdk-go/service/dataset"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// localObject is a file in a dataset directory, identified by the remote object key it maps to.
+type localObject struct {
+	Key     string
+	AbsPath string
+	Size    int64
+	ModTime time.Time
+}
+
+type changeType string
+
+const (
+	ChangeTypeAdded     changeType = "added"
+	ChangeTypeModified  changeType = "modified"
+	ChangeTypeDeleted   changeType = "deleted"
+	ChangeTypeUnchanged changeType = "unchanged"
+)
+
+// change is the difference between a local file and a remote object with the same key.
+// Added means the file only exists locally, and deleted means the object only exists remotely.
+type change struct {
+	Key    string
+	Type   changeType
+	Local  *localObject
+	Remote *data_storage.Object
+}
+
+// inRemoteObjectPrefix reports whether key is the object remoteObjectPrefix refers to, or an object under it.
+func inRemoteObjectPrefix(key string, remoteObjectPrefix string) bool {
+	prefix := dataset.CleanRemoteObjectPrefix(remoteObjectPrefix)
+	return prefix == "" || strings.HasPrefix(key, prefix) || key == strings.TrimSuffix(prefix, "/")
+}
+
+// listLocalObjects lists the files at absPath, which may be a file or a directory, keyed by their remote object keys.
+// A path that does not exist has no files.
+// Files and directories that matcher ignores are left out, unless matcher is nil.
+// The local state directory of the dataset, and the directories of other datasets nested in it, are always left out,
+// which datasetDirs holds among the directories of all datasets in the project.
+func listLocalObjects(matcher *ignoreMatcher, datasetDirPath string, datasetDirs map[string]bool, absPath string) (map[string]localObject, error) {
+
+	objects := map[string]localObject{}
+
+	if _, err := os.Stat(absPath); os.IsNotExist(err) {
+		return objects, nil
+	}
+
+	err := filepath.WalkDir(absPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && (path == filepath.Join(datasetDirPath, localStateDirName) || (path != datasetDirPath && datasetDirs[path])) {
+			return filepath.SkipDir
+		}
+		// skip special files, and downloads that are still being written or were interrupted
+		if !d.IsDir() && (!d.Type().IsRegular() || strings.HasSuffix(d.Name(), data_storage.DownloadTempSuffix)) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(datasetDirPath, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+
+		if matcher != nil {
+			if ignored, err := matcher.isIgnored(key, d.IsDir()); err != nil {
+				return err
+			} else if ignored && d.IsDir() {
+				return filepath.SkipDir
+			} else if ignored {
+				return nil
+			}
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		objects[key] = localObject{Key: key, AbsPath: path, Size: info.Size(), ModTime: info.ModTime()}
+
+		return nil
+	})
+
+	return objects, err
+}
+
+// listRemoteObjects lists the objects under remoteObjectPrefix, keyed by their keys.
+func listRemoteObjects(client data_storage.Client, remoteObjectPrefix string) (map[string]data_storage.Object, error) {
+
+	prefix := strings.TrimSuffix(dataset.CleanRemoteObjectPrefix(remoteObjectPrefix), "/")
+
+	list, err := client.ListObjects(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return remoteObjectsByKey(list, remoteObjectPrefix), nil
+}
+
+// remoteObjectsByKey keys the listed objects that are under remoteObjectPrefix by their keys.
+func remoteObjectsByKey(list []data_storage.Object, remoteObjectPrefix string) map[string]data_storage.Object {
+
+	objects := map[string]data_storage.Object{}
+	for _, o := range list {
+		// skip directory placeholders created by cloud consoles, and objects the CLI keeps for itself
+		if strings.HasSuffix(o.Key, "/") || isReservedKey(o.Key) || !inRemoteObjectPrefix(o.Key, remoteObjectPrefix) {
+			continue
+		}
+		objects[o.Key] = o
+	}
+
+	return objects
+}
+
+// getRemoteObject returns the object with the given key.
+func getRemoteObject(client data_storage.Client, remoteObjectKey string) (data_storage.Object, error) {
+
+	objects, err := listRemoteObjects(client, remoteObjectKey)
+	if err != nil {
+		return data_storage.Object{}, err
+	}
+
+	object, ok := objects[remoteObjectKey]
+	if !ok {
+		return data_storage.Object{}, errors.New(fmt.Sprintf("no object found with key %s", remoteObjectKey))
+	}
+
+	return object, nil
+}
+
+// compareObjects pairs up local files and remote objects by key, and works out how each pair differs.
+// Checksums of local files are taken from m where possible.
+// The changes are sorted by key.
+func compareObjects(m *manifest, localObjects map[string]localObject, remoteObjects map[string]data_storage.Object) ([]change, error) {
+
+	var changes []change
+
+	for key, l := range localObjects {
+		l := l
+		if r, ok := remoteObjects[key]; !ok {
+			changes = append(changes, change{Key: key, Type: ChangeTypeAdded, Local: &l})
+		} else if modified, err := isModified(m, l, r); err != nil {
+			return nil, err
+		} else if modified {
+			changes = append(changes, change{Key: key, Type: ChangeTypeModified, Local: &l, Remote: &r})
+		} else {
+			changes = append(changes, change{Key: key, Type: ChangeTypeUnchanged, Local: &l, Remote: &r})
+		}
+	}
+
+	for key, r := range remoteObjects {
+		r := r
+		if _, ok := localObjects[key]; !ok {
+			changes = append(changes, change{Key: key, Type: ChangeTypeDeleted, Remote: &r})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+
+	return changes, nil
+}
+
+// isModified compares a local file with a remote object by size, and by MD5 checksum when the remote object has one.
+// Without a checksum, the ETag recorded in m when the file was last transferred is compared instead,
+// Objects uploaded in parts have no checksum, so their ETags are compared with the ETag the file would have
+// if it was uploaded in parts the way push uploads it, when the object has as many parts.
+// If neither can be compared, the remote object is taken to differ if it was modified after the local file.
+func isModified(m *manifest, l localObject, r data_storage.Object) (bool, error) {
+	if l.Size != r.Size {
+		return true, nil
+	}
+
+	if r.MD5 != "" {
+		checksum, err := localObjectMD5(m, l)
+		if err != nil {
+			return false, err
+		}
+		return checksum != r.MD5, nil
+	}
+
+	if entry, ok := m.lookup(l); ok && entry.ETag != "" {
+		return entry.ETag != r.ETag, nil
+	}
+
+	partSize := partSizeFor(l.Size)
+	if parts, ok := multipartETagParts(r.ETag); ok && int64(parts) == (l.Size+partSize-1)/partSize {
+		eTag, err := multipartETag(l.AbsPath, l.Size, partSize)
+		if err != nil {
+			return false, err
+		}
+		return eTag != strings.Trim(r.ETag, `"`), nil
+	}
+
+	return r.LastModified.After(l.ModTime), nil
+}
+
+// multipartETagParts returns the number of parts of an object uploaded in parts from its ETag,
+// which is the checksum of its parts followed by their number, e.g. "9b2cf535f27731c974343645a3985328-2".
+func multipartETagParts(eTag string) (int, bool) {
+
+	checksum, parts, ok := strings.Cut(strings.Trim(eTag, `"`), "-")
+	if !ok || len(checksum) != 32 {
+		return 0, false
+	}
+
+	n, err := strconv.Atoi(parts)
+	if err != nil || n < 1 {
+		return 0, false
+	}
+
+	return n, true
+}
+
+// multipartETag returns the ETag, without quotes, of an object uploaded in parts of partSize from the file at path,
+// which is the MD5 checksum of the MD5 checksums of the parts, followed by the number of parts.
+func multipartETag(path string, size int64, partSize int64) (string, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	checksums := md5.New()
+	parts := 0
+	for offset := int64(0); offset < size; offset += partSize {
+		hash := md5.New()
+		if _, err := io.Copy(hash, io.NewSectionReader(file, offset, partSize)); err != nil {
+			return "", err
+		}
+		checksums.Write(hash.Sum(nil))
+		parts++
+	}
+
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(checksums.Sum(nil)), parts), nil
+}
+
+func fileMD5(path string) (string, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	hash := md5.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
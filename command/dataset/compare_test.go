@@ -0,0 +1,271 @@
+package dataset
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"github.com/deploifai/cli-go/command/dataset/data_storage"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestCompareObjects(t *testing.T) {
+	dir := t.TempDir()
+	modTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	localObjects := map[string]localObject{}
+	for key, content := range map[string]string{"added.txt": "new", "same.txt": "same", "changed.txt": "local"} {
+		absPath := filepath.Join(dir, key)
+		writeTestFile(t, absPath, content)
+		localObjects[key] = localObject{Key: key, AbsPath: absPath, Size: int64(len(content)), ModTime: modTime}
+	}
+
+	remoteObjects := map[string]data_storage.Object{
+		"same.txt":    {Key: "same.txt", Size: 4, MD5: testMD5("same")},
+		"changed.txt": {Key: "changed.txt", Size: 5, MD5: testMD5("other")},
+		"deleted.txt": {Key: "deleted.txt", Size: 7, MD5: testMD5("deleted")},
+	}
+
+	m := &manifest{Files: map[string]manifestEntry{}}
+	changes, err := compareObjects(m, localObjects, remoteObjects)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got [][2]string
+	for _, c := range changes {
+		got = append(got, [2]string{c.Key, string(c.Type)})
+	}
+	want := [][2]string{
+		{"added.txt", string(ChangeTypeAdded)},
+		{"changed.txt", string(ChangeTypeModified)},
+		{"deleted.txt", string(ChangeTypeDeleted)},
+		{"same.txt", string(ChangeTypeUnchanged)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("compareObjects() = %v, want %v", got, want)
+	}
+}
+
+func TestListLocalObjects(t *testing.T) {
+	projectDir := t.TempDir()
+	datasetDirPath := filepath.Join(projectDir, "data")
+	nestedDirPath := filepath.Join(datasetDirPath, "nested")
+
+	for _, name := range []string{
+		"a.txt",
+		"sub/b.txt",
+		// only the local state directory of the dataset itself is left out
+		localStateDirName + "/manifest.json",
+		"sub/" + localStateDirName + "/c.txt",
+		"nested/d.txt",
+		".e.txt.123" + data_storage.DownloadTempSuffix,
+	} {
+		writeTestFile(t, filepath.Join(datasetDirPath, filepath.FromSlash(name)), "x")
+	}
+
+	datasetDirs := map[string]bool{datasetDirPath: true, nestedDirPath: true}
+
+	tests := []struct {
+		name    string
+		absPath string
+		dirs    map[string]bool
+		want    []string
+	}{
+		{name: "dataset directory", absPath: datasetDirPath, dirs: datasetDirs, want: []string{"a.txt", "sub/.deploifai/c.txt", "sub/b.txt"}},
+		{name: "without nested datasets", absPath: datasetDirPath, dirs: nil, want: []string{"a.txt", "nested/d.txt", "sub/.deploifai/c.txt", "sub/b.txt"}},
+		{name: "subdirectory", absPath: filepath.Join(datasetDirPath, "sub"), dirs: datasetDirs, want: []string{"sub/.deploifai/c.txt", "sub/b.txt"}},
+		{name: "file", absPath: filepath.Join(datasetDirPath, "a.txt"), dirs: datasetDirs, want: []string{"a.txt"}},
+		{name: "missing path", absPath: filepath.Join(datasetDirPath, "missing"), dirs: datasetDirs, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects, err := listLocalObjects(nil, datasetDirPath, tt.dirs, tt.absPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var keys []string
+			for key := range objects {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			if !reflect.DeepEqual(keys, tt.want) {
+				t.Errorf("listLocalObjects() = %v, want %v", keys, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsModified(t *testing.T) {
+	dir := t.TempDir()
+	absPath := filepath.Join(dir, "a.txt")
+	writeTestFile(t, absPath, "hello")
+
+	modTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := localObject{Key: "a.txt", AbsPath: absPath, Size: 5, ModTime: modTime}
+
+	tests := []struct {
+		name     string
+		files    map[string]manifestEntry
+		remote   data_storage.Object
+		modified bool
+	}{
+		{
+			name:     "different size",
+			remote:   data_storage.Object{Key: "a.txt", Size: 6, MD5: testMD5("hello")},
+			modified: true,
+		},
+		{
+			name:     "same checksum",
+			remote:   data_storage.Object{Key: "a.txt", Size: 5, MD5: testMD5("hello")},
+			modified: false,
+		},
+		{
+			name:     "different checksum",
+			remote:   data_storage.Object{Key: "a.txt", Size: 5, MD5: testMD5("world")},
+			modified: true,
+		},
+		{
+			name:     "checksum recorded in the manifest",
+			files:    map[string]manifestEntry{"a.txt": {Size: 5, ModTime: modTime, MD5: testMD5("world")}},
+			remote:   data_storage.Object{Key: "a.txt", Size: 5, MD5: testMD5("world")},
+			modified: false,
+		},
+		{
+			name:     "file changed since its checksum was recorded",
+			files:    map[string]manifestEntry{"a.txt": {Size: 5, ModTime: modTime.Add(time.Hour), MD5: testMD5("world")}},
+			remote:   data_storage.Object{Key: "a.txt", Size: 5, MD5: testMD5("world")},
+			modified: true,
+		},
+		{
+			name:     "same ETag without a checksum",
+			files:    map[string]manifestEntry{"a.txt": {Size: 5, ModTime: modTime, ETag: `"abc-2"`}},
+			remote:   data_storage.Object{Key: "a.txt", Size: 5, ETag: `"abc-2"`},
+			modified: false,
+		},
+		{
+			name:     "different ETag without a checksum",
+			files:    map[string]manifestEntry{"a.txt": {Size: 5, ModTime: modTime, ETag: `"abc-2"`}},
+			remote:   data_storage.Object{Key: "a.txt", Size: 5, ETag: `"def-2"`},
+			modified: true,
+		},
+		{
+			name:     "same multipart ETag",
+			remote:   data_storage.Object{Key: "a.txt", Size: 5, ETag: `"` + testMultipartETag("hello") + `"`, LastModified: modTime.Add(time.Hour)},
+			modified: false,
+		},
+		{
+			// the remote object being older does not hide a change of the same size
+			name:     "different multipart ETag",
+			remote:   data_storage.Object{Key: "a.txt", Size: 5, ETag: `"` + testMultipartETag("world") + `"`, LastModified: modTime.Add(-time.Hour)},
+			modified: true,
+		},
+		{
+			name:     "remote object older than the file, without a checksum or ETag",
+			remote:   data_storage.Object{Key: "a.txt", Size: 5, ETag: `"abc-2"`, LastModified: modTime.Add(-time.Hour)},
+			modified: false,
+		},
+		{
+			name:     "remote object newer than the file, without a checksum or ETag",
+			remote:   data_storage.Object{Key: "a.txt", Size: 5, ETag: `"abc-2"`, LastModified: modTime.Add(time.Hour)},
+			modified: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := tt.files
+			if files == nil {
+				files = map[string]manifestEntry{}
+			}
+
+			modified, err := isModified(&manifest{Files: files}, l, tt.remote)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if modified != tt.modified {
+				t.Errorf("isModified() = %v, want %v", modified, tt.modified)
+			}
+		})
+	}
+}
+
+func TestMultipartETag(t *testing.T) {
+	absPath := filepath.Join(t.TempDir(), "a.txt")
+	writeTestFile(t, absPath, "hello")
+
+	tests := []struct {
+		partSize int64
+		want     string
+	}{
+		{partSize: 5, want: testMultipartETag("hello")},
+		{partSize: 2, want: testMultipartETag("he", "ll", "o")},
+		{partSize: 3, want: testMultipartETag("hel", "lo")},
+	}
+
+	for _, tt := range tests {
+		eTag, err := multipartETag(absPath, 5, tt.partSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if eTag != tt.want {
+			t.Errorf("multipartETag() with parts of %d bytes = %s, want %s", tt.partSize, eTag, tt.want)
+		}
+	}
+}
+
+func TestMultipartETagParts(t *testing.T) {
+	tests := []struct {
+		eTag  string
+		parts int
+		ok    bool
+	}{
+		{eTag: `"9b2cf535f27731c974343645a3985328-2"`, parts: 2, ok: true},
+		{eTag: "9b2cf535f27731c974343645a3985328-10000", parts: 10000, ok: true},
+		{eTag: `"9b2cf535f27731c974343645a3985328"`},
+		{eTag: `"9b2cf535f27731c974343645a3985328-0"`},
+		{eTag: `"9b2cf535f27731c974343645a3985328-x"`},
+		{eTag: `"abc-2"`},
+		{eTag: "CJ+Bj9Xm/fwCEAE="},
+	}
+
+	for _, tt := range tests {
+		parts, ok := multipartETagParts(tt.eTag)
+		if parts != tt.parts || ok != tt.ok {
+			t.Errorf("multipartETagParts(%s) = %d, %v, want %d, %v", tt.eTag, parts, ok, tt.parts, tt.ok)
+		}
+	}
+}
+
+func testMD5(content string) string {
+	checksum := md5.Sum([]byte(content))
+	return hex.EncodeToString(checksum[:])
+}
+
+func writeTestFile(t *testing.T, path string, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testMultipartETag returns the ETag, without quotes, of an object uploaded in the given parts.
+func testMultipartETag(parts ...string) string {
+	var checksums []byte
+	for _, part := range parts {
+		checksum := md5.Sum([]byte(part))
+		checksums = append(checksums, checksum[:]...)
+	}
+	return fmt.Sprintf("%s-%d", testMD5(string(checksums)), len(parts))
+}
@@ -0,0 +1,295 @@
+/*
+Copyright © 2023 Sean Chok
+*/
+package dataset
+
+import (
+	"errors"
+	"fmt"
+	"github.com/deploifai/cli-go/command/ctx"
+	"github.com/deploifai/cli-go/command/dataset/data_storage"
+	"github.com/deploifai/sdk-go/api/generated"
+	"github.com/deploifai/sdk-go/service/dataset"
+	"github.com/spf13/cobra"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var cpRecursive bool
+var cpTo string
+
+// cpCmd represents the cp command
+var cpCmd = &cobra.Command{
+	Use:   "cp <src> <dest>",
+	Short: "Copy files or directories within a dataset, or to another dataset",
+	Long: `Copy files or directories in a dataset to other paths, without downloading them to the local filesystem.
+Local files are not changed.
+
+This requires the local directory to be initialised as a dataset first.
+Use the command "deploifai dataset init" to do that.
+
+<src> and <dest> are resolved the same way as for "deploifai dataset pull".
+Use -r to copy a directory, in which case the objects under <src> are copied to the same paths under <dest>.
+A file is copied into <dest> if <dest> ends with a "/", or to <dest> itself otherwise.
+
+Use --to to copy to another dataset in the same project, in which case <dest> is relative to the root of that dataset.
+
+Objects are copied by the cloud provider where possible, otherwise they are streamed through this machine.
+`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return copyObjects(cmd, args[0], args[1], cpRecursive, cpTo, false)
+	},
+}
+
+func init() {
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// cpCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// cpCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	cpCmd.Flags().BoolVarP(&cpRecursive, "recursive", "r", false, "copy directories and all the objects in them")
+	cpCmd.Flags().StringVar(&cpTo, "to", "", "name of another dataset in the project to copy to")
+}
+
+// copyPair is an object and the key it is copied to.
+type copyPair struct {
+	Src     data_storage.Object
+	DestKey string
+}
+
+// copyObjects copies, or with move, moves, the file or directory at srcArg to destArg, for "dataset cp" and "dataset mv".
+func copyObjects(cmd *cobra.Command, srcArg string, destArg string, recursive bool, toDataset string, move bool) error {
+
+	verb := "copy"
+	if move {
+		verb = "move"
+	}
+
+	_context := ctx.GetContextValue(cmd)
+
+	// get the dataset and directory path from config
+	ok, ds, datasetDirPath, err := getTargetDataset(cmd)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("the current directory is not initialised as a dataset, use --dataset to choose one")
+	}
+
+	opts, err := newTransferOptions(_context.Root.Transfer, 0, "", false)
+	if err != nil {
+		return err
+	}
+
+	srcClient, err := data_storage.New(cmd.Context(), _context.ServiceClientConfig.API, ds.ID, opts.Traffic)
+	if err != nil {
+		return err
+	}
+
+	srcKey, srcObjectType, err := getCopySource(srcClient, datasetDirPath, srcArg)
+	if err != nil {
+		return err
+	}
+	if srcObjectType == ObjectTypeDirectory && !recursive {
+		return errors.New(fmt.Sprintf("%s is a directory, use -r to %s it", srcArg, verb))
+	}
+
+	destClient := srcClient
+	sameDataset := true
+	var destKey string
+
+	if toDataset != "" {
+		client := dataset.NewFromConfig(*_context.ServiceClientConfig)
+		whereAccount := generated.AccountWhereUniqueInput{Username: &_context.Root.Workspace.Username}
+
+		dataStorage, err := findDataStorage(cmd.Context(), *client, whereAccount, _context.Project.Project.ID, toDataset)
+		if err != nil {
+			return err
+		}
+
+		// paths in another dataset are relative to its root
+		destKey = path.Clean(strings.TrimPrefix(filepath.ToSlash(destArg), "/"))
+		if destKey == ".." || strings.HasPrefix(destKey, "../") {
+			return errors.New(fmt.Sprintf("invalid path: %s", destArg))
+		}
+
+		if dataStorage.GetID() != ds.ID {
+			sameDataset = false
+			if destClient, err = data_storage.New(cmd.Context(), _context.ServiceClientConfig.API, dataStorage.GetID(), opts.Traffic); err != nil {
+				return err
+			}
+		}
+	} else {
+		absPaths, err := getAbsPaths(datasetDirPath, []string{destArg})
+		if err != nil {
+			return err
+		}
+
+		if ok, invalidArgs, err := verifyPullPaths(datasetDirPath, []string{destArg}, absPaths); err != nil {
+			return err
+		} else if !ok {
+			return errors.New(fmt.Sprintf("invalid paths: %s", strings.Join(invalidArgs, ", ")))
+		}
+
+		remoteObjectPrefixes, err := getRemoteObjectPrefixes(datasetDirPath, absPaths)
+		if err != nil {
+			return err
+		}
+		destKey = filepath.ToSlash(remoteObjectPrefixes[0])
+	}
+
+	if destKey == "." {
+		destKey = ""
+	}
+
+	var pairs []copyPair
+
+	if srcObjectType == ObjectTypeDirectory {
+		if sameDataset && (destKey == srcKey || srcKey == "" || strings.HasPrefix(destKey, srcKey+"/")) {
+			return errors.New(fmt.Sprintf("cannot %s %s into itself", verb, srcArg))
+		}
+
+		remoteObjects, err := listRemoteObjects(srcClient, srcKey)
+		if err != nil {
+			return err
+		}
+
+		for key, r := range remoteObjects {
+			pairs = append(pairs, copyPair{Src: r, DestKey: path.Join(destKey, strings.TrimPrefix(key, srcKey+"/"))})
+		}
+	} else {
+		r, err := getRemoteObject(srcClient, srcKey)
+		if err != nil {
+			return err
+		}
+
+		if destKey == "" || strings.HasSuffix(destArg, "/") {
+			destKey = path.Join(destKey, path.Base(srcKey))
+		}
+		if sameDataset && destKey == srcKey {
+			return errors.New(fmt.Sprintf("cannot %s %s onto itself", verb, srcArg))
+		}
+
+		pairs = append(pairs, copyPair{Src: r, DestKey: destKey})
+	}
+
+	for _, p := range pairs {
+		if isReservedKey(p.DestKey) || p.DestKey == localStateDirName {
+			return errors.New(fmt.Sprintf("cannot %s to %s, %s is reserved for the CLI", verb, destArg, reservedKeyPrefix))
+		}
+	}
+
+	// moved objects are forgotten in the manifest, so that their local files are pushed again if they still exist
+	m, _, _ := loadManifest(datasetDirPath)
+
+	transfer := func(p copyPair) error {
+		if err := copyObject(srcClient, destClient, opts, sameDataset, p.Src, p.DestKey); err != nil {
+			return err
+		}
+		if move {
+			if err := srcClient.DeleteObject(p.Src.Key); err != nil {
+				return err
+			}
+			m.remove(p.Src.Key)
+		}
+		return nil
+	}
+
+	if srcObjectType == ObjectTypeDirectory {
+		files := make([]transferFile, len(pairs))
+		for i, p := range pairs {
+			files[i] = transferFile{Name: p.Src.Key, Size: p.Src.Size}
+		}
+
+		err = runDir(opts, fmt.Sprintf("%s -> %s", srcArg, destArg), files, func(i int) error {
+			return transfer(pairs[i])
+		})
+	} else {
+		f := func() error {
+			return transfer(pairs[0])
+		}
+
+		verbing, verbed := "Copying", "Copied"
+		if move {
+			verbing, verbed = "Moving", "Moved"
+		}
+		prefixMessage := fmt.Sprintf("%s %s -> %s ", verbing, srcKey, pairs[0].DestKey)
+		finalMessage := fmt.Sprintf("%s %s -> %s", verbed, srcKey, pairs[0].DestKey)
+
+		err = runFile(opts, f, prefixMessage, finalMessage)
+	}
+
+	if move {
+		if saveErr := m.save(); err == nil {
+			err = saveErr
+		}
+	}
+
+	return err
+}
+
+// getCopySource resolves srcArg to a remote object key, and whether it is a file or a directory.
+func getCopySource(client data_storage.Client, datasetDirPath string, srcArg string) (string, objectType, error) {
+
+	absPaths, err := getAbsPaths(datasetDirPath, []string{srcArg})
+	if err != nil {
+		return "", 0, err
+	}
+
+	if ok, invalidArgs, err := verifyPullPaths(datasetDirPath, []string{srcArg}, absPaths); err != nil {
+		return "", 0, err
+	} else if !ok {
+		return "", 0, errors.New(fmt.Sprintf("invalid paths: %s", strings.Join(invalidArgs, ", ")))
+	}
+
+	remoteObjectPrefixes, err := getRemoteObjectPrefixes(datasetDirPath, absPaths)
+	if err != nil {
+		return "", 0, err
+	}
+
+	ok, objectTypes, invalid, err := verifyRemoteObjectPrefixes(client, []string{srcArg}, remoteObjectPrefixes)
+	if err != nil {
+		return "", 0, err
+	} else if !ok {
+		return "", 0, errors.New(fmt.Sprintf("no objects found in paths: %s", strings.Join(invalid, ", ")))
+	}
+
+	key := filepath.ToSlash(remoteObjectPrefixes[0])
+	if key == "." {
+		key = ""
+	}
+
+	return key, objectTypes[0], nil
+}
+
+// copyObject copies an object to destKey, on the cloud provider's side when both keys are in the same dataset,
+// or by streaming it from srcClient to destClient otherwise, or if the cloud provider cannot copy it.
+func copyObject(srcClient data_storage.Client, destClient data_storage.Client, opts transferOptions, sameDataset bool, src data_storage.Object, destKey string) error {
+
+	if sameDataset {
+		if err := srcClient.CopyObject(src, destKey); !errors.Is(err, data_storage.ErrCopyUnsupported) {
+			return err
+		}
+	}
+
+	reader, err := srcClient.OpenObject(src.Key)
+	if err != nil {
+		return err
+	}
+	defer func(reader io.ReadCloser) {
+		_ = reader.Close()
+	}(reader)
+
+	// objects that are too large to upload at once, e.g. above 5 GiB on S3, are uploaded in parts
+	_, err = uploadStream(destClient, opts, destKey, reader, src.Size)
+
+	return err
+}
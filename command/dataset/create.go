@@ -0,0 +1,302 @@
+/*
+Copyright © 2023 Sean Chok
+*/
+package dataset
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/deploifai/cli-go/command/command_config/project_config"
+	"github.com/deploifai/cli-go/command/ctx"
+	"github.com/deploifai/cli-go/utils/spinner_utils"
+	"github.com/deploifai/sdk-go/api"
+	"github.com/deploifai/sdk-go/api/generated"
+	"github.com/deploifai/sdk-go/service/cloud_profile"
+	"github.com/deploifai/sdk-go/service/dataset"
+	"github.com/deploifai/sdk-go/service/project"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+// deployPollInterval is how often the deploy status of a new dataset is checked
+const deployPollInterval = 5 * time.Second
+
+// the SDK has no operations to create a data storage, to get its status or to list data storages with their status,
+// so they are in query.gql and sent with the GraphQL client of the SDK.
+// Nothing checks them against the API schema, so a change to the API only shows up when they are sent,
+// as an error that names the operation.
+//
+//go:embed query.gql
+var queryDocument string
+
+type dataStorageStatus struct {
+	ID     string                      `json:"id"`
+	Name   string                      `json:"name"`
+	Status generated.DataStorageStatus `json:"status"`
+}
+
+var createNotProjectCloudProfile = false
+var createRegion string
+var createZone string
+var createInit = false
+
+// createCmd represents the create command
+var createCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a new dataset in the current project",
+	Long: `Create a new dataset in the current project, and wait for its cloud storage to be deployed.
+
+The dataset is created with the cloud profile of the project, use --not-project-cp to select another cloud profile.
+Use --region, and --zone for GCP, to choose where the dataset is stored, instead of the default of the cloud profile.
+
+Use --init to initialise the current directory as the new dataset once it is deployed, as "deploifai dataset init" does.
+`,
+	Args: cobra.ExactArgs(1), // requires exactly 1 arg, which is the name of the new dataset
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		dataStorageName := args[0]
+
+		_context := ctx.GetContextValue(cmd)
+
+		if !_context.Project.Project.IsInitialized() {
+			return project_config.ProjectNotInitializedError{}
+		}
+
+		// verify if the current directory can be initialised as the new dataset, before creating it
+		if createInit {
+			if ok, _, _, err := getDataset(*_context.Project); err != nil {
+				return err
+			} else if ok {
+				return errors.New("the current directory is already initialised as a dataset")
+			}
+		}
+
+		projectId := _context.Project.Project.ID
+
+		client := dataset.NewFromConfig(*_context.ServiceClientConfig)
+		whereAccount := generated.AccountWhereUniqueInput{Username: &_context.Root.Workspace.Username}
+
+		// check if the dataset name already exists in the project, whatever its status
+		if dataStorages, err := client.List(cmd.Context(), whereAccount, &generated.DataStorageWhereInput{
+			Projects: &generated.ProjectListRelationFilter{Some: &generated.ProjectWhereInput{ID: &generated.StringFilter{Equals: &projectId}}},
+			Name:     &generated.StringFilter{Equals: &dataStorageName},
+		}); err != nil {
+			return err
+		} else if len(dataStorages) > 0 {
+			return errors.New(fmt.Sprintf("%s already exists in the current project", dataStorageName))
+		}
+
+		cloudProfile, err := getCreateCloudProfile(cmd, whereAccount, projectId)
+		if err != nil {
+			return err
+		}
+
+		yodaConfig, err := getYodaConfig(cloudProfile.Provider, createRegion, createZone)
+		if err != nil {
+			return err
+		}
+
+		dataStorage, err := createDataStorage(cmd.Context(), _context.ServiceClientConfig.API, whereAccount, projectId, generated.CreateDataStorageInput{
+			Name:                    dataStorageName,
+			CloudProfileID:          cloudProfile.GetID(),
+			CloudProviderYodaConfig: yodaConfig,
+		})
+		if err != nil {
+			return err
+		}
+
+		status, err := waitForDeploy(cmd.Context(), _context.ServiceClientConfig.API, dataStorage)
+		if err != nil {
+			return err
+		}
+		if status != generated.DataStorageStatusDeploySuccess {
+			return errors.New(fmt.Sprintf("dataset %s failed to deploy, its status is %s", dataStorageName, status))
+		}
+
+		cmd.Printf("Successfully created dataset %s\n", dataStorageName)
+
+		if createInit {
+			if err = saveInConfig(_context.Project, dataStorage.ID); err != nil {
+				return err
+			}
+			cmd.Printf("Initialised the current directory as dataset %s\n", dataStorageName)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// createCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// createCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	createCmd.Flags().BoolVar(&createNotProjectCloudProfile, "not-project-cp", false, "select a cloud profile instead of using the cloud profile of the project to create the dataset")
+	createCmd.Flags().StringVar(&createRegion, "region", "", "region of the cloud provider to store the dataset in")
+	createCmd.Flags().StringVar(&createZone, "zone", "", "zone of the region to store the dataset in, required with --region for GCP")
+	createCmd.Flags().BoolVar(&createInit, "init", false, "initialise the current directory as the new dataset")
+}
+
+// getCreateCloudProfile returns the cloud profile of the project, or with --not-project-cp, the one the user selects.
+func getCreateCloudProfile(cmd *cobra.Command, whereAccount generated.AccountWhereUniqueInput, projectId string) (generated.CloudProfileFragment, error) {
+
+	_context := ctx.GetContextValue(cmd)
+
+	cloudProfileClient := cloud_profile.NewFromConfig(*_context.ServiceClientConfig)
+
+	if createNotProjectCloudProfile {
+		return chooseCloudProfile(cmd.Context(), *cloudProfileClient, whereAccount)
+	}
+
+	projectClient := project.NewFromConfig(*_context.ServiceClientConfig)
+
+	projects, err := projectClient.List(cmd.Context(), whereAccount, &generated.ProjectWhereInput{ID: &generated.StringFilter{Equals: &projectId}})
+	if err != nil {
+		return generated.CloudProfileFragment{}, err
+	}
+	if len(projects) == 0 {
+		return generated.CloudProfileFragment{}, errors.New("the current project is not found in the current workspace")
+	}
+	if projects[0].GetCloudProfileID() == nil {
+		return generated.CloudProfileFragment{}, errors.New("the current project has no cloud profile, use --not-project-cp to select one")
+	}
+
+	return cloudProfileClient.Get(cmd.Context(), generated.CloudProfileWhereUniqueInput{ID: projects[0].GetCloudProfileID()})
+}
+
+func chooseCloudProfile(c context.Context, client cloud_profile.Client, whereAccount generated.AccountWhereUniqueInput) (generated.CloudProfileFragment, error) {
+
+	cloudProfiles, err := client.List(c, whereAccount, nil)
+	if err != nil {
+		return generated.CloudProfileFragment{}, err
+	}
+
+	if len(cloudProfiles) == 0 {
+		return generated.CloudProfileFragment{}, errors.New("no cloud profiles found in the current workspace, please create one first")
+	}
+
+	options := make([]string, len(cloudProfiles))
+	for i, cp := range cloudProfiles {
+		options[i] = fmt.Sprintf("%s <%s>", cp.GetName(), cp.GetProvider())
+	}
+
+	var index int
+	err = survey.AskOne(&survey.Select{
+		Message: "Choose a cloud profile",
+		Options: options,
+	}, &index, survey.WithPageSize(10))
+	if err != nil {
+		return generated.CloudProfileFragment{}, err
+	}
+
+	return cloudProfiles[index], nil
+}
+
+// getYodaConfig returns the storage config for --region and --zone, or nil to use the defaults of the cloud profile.
+func getYodaConfig(provider generated.CloudProvider, region string, zone string) (*generated.CreateCloudProviderYodaConfig, error) {
+
+	if region == "" {
+		if zone != "" {
+			return nil, errors.New("--zone requires --region")
+		}
+		return nil, nil
+	}
+
+	switch provider {
+	case generated.CloudProviderAws:
+		return &generated.CreateCloudProviderYodaConfig{AwsConfig: &generated.CreateAWSYodaConfig{AwsRegion: region}}, nil
+	case generated.CloudProviderAzure:
+		return &generated.CreateCloudProviderYodaConfig{AzureConfig: &generated.CreateAzureYodaConfig{AzureRegion: region}}, nil
+	case generated.CloudProviderGcp:
+		if zone == "" {
+			return nil, errors.New("--zone is required with --region for GCP")
+		}
+		return &generated.CreateCloudProviderYodaConfig{GcpConfig: &generated.CreateGCPYodaConfig{GcpRegion: region, GcpZone: zone}}, nil
+	default:
+		return nil, errors.New(fmt.Sprintf("unsupported cloud provider: %s", provider))
+	}
+}
+
+func createDataStorage(c context.Context, provider api.Provider, whereAccount generated.AccountWhereUniqueInput, projectId string, data generated.CreateDataStorageInput) (dataStorageStatus, error) {
+
+	spinner := spinner_utils.NewAPICallSpinner()
+	spinner.Suffix = " Creating dataset... "
+
+	spinner.Start()
+	defer spinner.Stop()
+
+	var res struct {
+		CreateDataStorage dataStorageStatus `json:"createDataStorage"`
+	}
+
+	err := postGQL(c, provider, "CreateDataStorage", &res, map[string]interface{}{
+		"whereAccount": whereAccount,
+		"data":         data,
+		"whereProject": generated.ProjectWhereUniqueInput{ID: &projectId},
+	})
+
+	return res.CreateDataStorage, err
+}
+
+// waitForDeploy polls the status of a new dataset until it is no longer being deployed, and returns it.
+func waitForDeploy(c context.Context, provider api.Provider, dataStorage dataStorageStatus) (generated.DataStorageStatus, error) {
+
+	spinner := spinner_utils.NewSleepSpinner()
+	spinner.Suffix = fmt.Sprintf(" Deploying dataset %s, this can take a few minutes... ", dataStorage.Name)
+
+	spinner.Start()
+	defer spinner.Stop()
+
+	status := dataStorage.Status
+
+	for status == generated.DataStorageStatusUndeployed || status == generated.DataStorageStatusDeploying {
+
+		select {
+		case <-c.Done():
+			return status, c.Err()
+		case <-time.After(deployPollInterval):
+		}
+
+		var res struct {
+			DataStorage *dataStorageStatus `json:"dataStorage"`
+		}
+
+		if err := postGQL(c, provider, "GetDataStorageStatus", &res, map[string]interface{}{
+			"where": generated.DataStorageWhereUniqueInput{ID: &dataStorage.ID},
+		}); err != nil {
+			return status, err
+		}
+		if res.DataStorage == nil {
+			return status, errors.New(fmt.Sprintf("dataset %s is not found", dataStorage.Name))
+		}
+
+		status = res.DataStorage.Status
+	}
+
+	return status, nil
+}
+
+// postGQL sends an operation of query.gql, by name, with the GraphQL client of the SDK.
+func postGQL(c context.Context, provider api.Provider, operationName string, res interface{}, vars map[string]interface{}) error {
+
+	client, ok := provider.GetGQLClient().(*generated.Client)
+	if !ok {
+		return errors.New(fmt.Sprintf("cannot send %s, the GraphQL client of the SDK is a %T instead of a *generated.Client", operationName, provider.GetGQLClient()))
+	}
+
+	if err := client.Client.Post(c, operationName, queryDocument, res, vars); err != nil {
+		return fmt.Errorf("%s failed: %w", operationName, provider.ProcessGQLError(err))
+	}
+
+	return nil
+}
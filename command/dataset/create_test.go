@@ -0,0 +1,59 @@
+package dataset
+
+import (
+	"context"
+	"github.com/deploifai/sdk-go/api"
+	"github.com/deploifai/sdk-go/api/generated"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeGQLClient is a GraphQL client that is not the generated client of the SDK.
+type fakeGQLClient struct {
+	generated.GQLClient
+}
+
+func TestPostGQL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"errors": [{"message": "Unknown argument \"whereProject\""}]}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		provider api.Provider
+		wantErr  []string
+	}{
+		{
+			name:     "client that is not generated",
+			provider: api.API{GQLClient: fakeGQLClient{}},
+			wantErr:  []string{"cannot send CreateDataStorage", "dataset.fakeGQLClient"},
+		},
+		{
+			name:     "operation that the API rejects",
+			provider: api.NewAPI(server.URL, server.URL, nil),
+			wantErr:  []string{"CreateDataStorage failed", "whereProject"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var res struct {
+				CreateDataStorage dataStorageStatus `json:"createDataStorage"`
+			}
+
+			err := postGQL(context.Background(), tt.provider, "CreateDataStorage", &res, map[string]interface{}{})
+			if err == nil {
+				t.Fatal("postGQL() returned no error")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("postGQL() returned %q, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,344 @@
+package data_storage
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/deploifai/sdk-go/api/generated"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// AWSClient is a Client for a data storage backed by an AWS S3 bucket.
+type AWSClient struct {
+	ctx     context.Context
+	service *s3.Client
+	bucket  string
+}
+
+func NewAWSClient(ctx context.Context, awsConfig *generated.AWSYodaConfigFragment, bucket string, traffic *Traffic) (*AWSClient, error) {
+
+	if awsConfig.GetAwsAccessKey() == nil || awsConfig.GetAwsSecretAccessKey() == nil {
+		return nil, errors.New("AWS credentials of the dataset are not available")
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(*awsConfig.GetAwsAccessKey(), *awsConfig.GetAwsSecretAccessKey(), "")),
+		config.WithRegion(awsConfig.GetAwsRegion()),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	service := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if traffic != nil {
+			var client s3.HTTPClient = awshttp.NewBuildableClient()
+			if o.HTTPClient != nil {
+				client = o.HTTPClient
+			}
+			o.HTTPClient = &trafficClient{client: client, traffic: traffic}
+		}
+	})
+
+	return &AWSClient{ctx: ctx, service: service, bucket: bucket}, nil
+}
+
+func (r *AWSClient) ListObjects(prefix string) (objects []Object, err error) {
+
+	pager := s3.NewListObjectsV2Paginator(r.service, &s3.ListObjectsV2Input{
+		Bucket: &r.bucket,
+		Prefix: &prefix,
+	})
+
+	for pager.HasMorePages() {
+		page, err := pager.NextPage(r.ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, o := range page.Contents {
+			object := Object{Key: *o.Key, Size: o.Size}
+			if o.ETag != nil {
+				object.ETag = *o.ETag
+				object.MD5 = md5FromETag(*o.ETag)
+			}
+			if o.LastModified != nil {
+				object.LastModified = *o.LastModified
+			}
+			objects = append(objects, object)
+		}
+	}
+
+	return objects, nil
+}
+
+// ListObjectVersions lists the current versions of the objects, with their version ids.
+// Objects in a bucket without versioning have no version.
+func (r *AWSClient) ListObjectVersions(prefix string) (objects []Object, err error) {
+
+	params := &s3.ListObjectVersionsInput{
+		Bucket: &r.bucket,
+		Prefix: &prefix,
+	}
+
+	for {
+		page, err := r.service.ListObjectVersions(r.ctx, params)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, o := range page.Versions {
+			// older versions are not current objects, and a deleted object has a delete marker as its latest version instead
+			if !o.IsLatest {
+				continue
+			}
+
+			object := Object{Key: *o.Key, Size: o.Size}
+			if o.ETag != nil {
+				object.ETag = *o.ETag
+				object.MD5 = md5FromETag(*o.ETag)
+			}
+			if o.LastModified != nil {
+				object.LastModified = *o.LastModified
+			}
+			if o.VersionId != nil && *o.VersionId != "null" {
+				object.Version = *o.VersionId
+			}
+			objects = append(objects, object)
+		}
+
+		if !page.IsTruncated {
+			return objects, nil
+		}
+		params.KeyMarker, params.VersionIdMarker = page.NextKeyMarker, page.NextVersionIdMarker
+	}
+}
+
+func (r *AWSClient) UploadFile(input UploadFileInput) (eTag string, err error) {
+
+	file, err := os.Open(input.SrcAbsPath)
+	if err != nil {
+		return "", err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	params := &s3.PutObjectInput{
+		Bucket: &r.bucket,
+		Key:    &input.RemoteObjectKey,
+		Body:   file,
+	}
+
+	if input.MD5 != "" {
+		checksum, err := hex.DecodeString(input.MD5)
+		if err != nil {
+			return "", err
+		}
+		contentMD5 := base64.StdEncoding.EncodeToString(checksum)
+		params.ContentMD5 = &contentMD5
+	}
+
+	output, err := r.service.PutObject(r.ctx, params)
+	if err != nil {
+		return "", err
+	}
+
+	if output.ETag != nil {
+		eTag = *output.ETag
+	}
+
+	return eTag, nil
+}
+
+func (r *AWSClient) DownloadFile(remoteObjectKey string, destAbsPath string) error {
+	return r.DownloadFileVersion(remoteObjectKey, "", destAbsPath)
+}
+
+func (r *AWSClient) DownloadFileVersion(remoteObjectKey string, version string, destAbsPath string) error {
+
+	params := &s3.GetObjectInput{
+		Bucket: &r.bucket,
+		Key:    &remoteObjectKey,
+	}
+	if version != "" {
+		params.VersionId = &version
+	}
+
+	object, err := r.service.GetObject(r.ctx, params)
+	if err != nil {
+		return err
+	}
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(object.Body)
+
+	return writeFile(destAbsPath, object.Body)
+}
+
+func (r *AWSClient) DeleteObject(remoteObjectKey string) error {
+
+	_, err := r.service.DeleteObject(r.ctx, &s3.DeleteObjectInput{
+		Bucket: &r.bucket,
+		Key:    &remoteObjectKey,
+	})
+
+	return err
+}
+
+// maxCopyObjectSize is the size of the largest object S3 can copy in a single CopyObject request.
+const maxCopyObjectSize = 5 * 1024 * 1024 * 1024
+
+func (r *AWSClient) CopyObject(src Object, destRemoteObjectKey string) error {
+
+	if src.Size > maxCopyObjectSize {
+		return ErrCopyUnsupported
+	}
+
+	copySource := url.PathEscape(r.bucket + "/" + src.Key)
+
+	_, err := r.service.CopyObject(r.ctx, &s3.CopyObjectInput{
+		Bucket:     &r.bucket,
+		Key:        &destRemoteObjectKey,
+		CopySource: &copySource,
+	})
+
+	return err
+}
+
+func (r *AWSClient) OpenObject(remoteObjectKey string) (io.ReadCloser, error) {
+	return r.OpenObjectRange(remoteObjectKey, 0, -1)
+}
+
+func (r *AWSClient) OpenObjectRange(remoteObjectKey string, offset int64, length int64) (io.ReadCloser, error) {
+
+	params := &s3.GetObjectInput{
+		Bucket: &r.bucket,
+		Key:    &remoteObjectKey,
+	}
+
+	// ranges are HTTP byte ranges, whose ends are inclusive
+	if length >= 0 {
+		byteRange := fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+		params.Range = &byteRange
+	} else if offset > 0 {
+		byteRange := fmt.Sprintf("bytes=%d-", offset)
+		params.Range = &byteRange
+	}
+
+	object, err := r.service.GetObject(r.ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return object.Body, nil
+}
+
+func (r *AWSClient) UploadStream(remoteObjectKey string, reader io.Reader, size int64) (eTag string, err error) {
+
+	// the content length has to be known up front, as the stream cannot be read twice to sign it
+	output, err := r.service.PutObject(r.ctx, &s3.PutObjectInput{
+		Bucket:        &r.bucket,
+		Key:           &remoteObjectKey,
+		Body:          reader,
+		ContentLength: size,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if output.ETag != nil {
+		eTag = *output.ETag
+	}
+
+	return eTag, nil
+}
+
+func (r *AWSClient) CreateMultipartUpload(remoteObjectKey string) (uploadId string, err error) {
+
+	output, err := r.service.CreateMultipartUpload(r.ctx, &s3.CreateMultipartUploadInput{
+		Bucket: &r.bucket,
+		Key:    &remoteObjectKey,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return *output.UploadId, nil
+}
+
+func (r *AWSClient) UploadPart(remoteObjectKey string, uploadId string, partNumber int, reader io.ReadSeeker, size int64) (eTag string, err error) {
+
+	output, err := r.service.UploadPart(r.ctx, &s3.UploadPartInput{
+		Bucket:        &r.bucket,
+		Key:           &remoteObjectKey,
+		UploadId:      &uploadId,
+		PartNumber:    int32(partNumber),
+		Body:          reader,
+		ContentLength: size,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if output.ETag != nil {
+		eTag = *output.ETag
+	}
+
+	return eTag, nil
+}
+
+func (r *AWSClient) CompleteMultipartUpload(remoteObjectKey string, uploadId string, parts []Part, md5 string) (eTag string, err error) {
+
+	// S3 does not keep a checksum of the whole object for multipart uploads, so md5 is not used
+	completedParts := make([]types.CompletedPart, len(parts))
+	for i, p := range parts {
+		completedParts[i] = types.CompletedPart{ETag: &parts[i].ETag, PartNumber: int32(p.Number)}
+	}
+
+	output, err := r.service.CompleteMultipartUpload(r.ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          &r.bucket,
+		Key:             &remoteObjectKey,
+		UploadId:        &uploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completedParts},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if output.ETag != nil {
+		eTag = *output.ETag
+	}
+
+	return eTag, nil
+}
+
+func (r *AWSClient) AbortMultipartUpload(remoteObjectKey string, uploadId string) error {
+
+	_, err := r.service.AbortMultipartUpload(r.ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   &r.bucket,
+		Key:      &remoteObjectKey,
+		UploadId: &uploadId,
+	})
+
+	return err
+}
+
+// md5FromETag returns the MD5 checksum an S3 ETag represents.
+// ETags of multipart uploads are not checksums of the content, and they contain a '-'.
+func md5FromETag(etag string) string {
+	etag = strings.Trim(etag, `"`)
+	if len(etag) != 32 || strings.Contains(etag, "-") {
+		return ""
+	}
+	return etag
+}
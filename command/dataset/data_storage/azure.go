@@ -0,0 +1,280 @@
+package data_storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/deploifai/sdk-go/api/generated"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// AzureClient is a Client for a data storage backed by an Azure Blob Storage container.
+type AzureClient struct {
+	ctx       context.Context
+	service   *azblob.Client
+	container string
+}
+
+func NewAzureClient(ctx context.Context, azureConfig *generated.AzureYodaConfigFragment, container string, traffic *Traffic) (*AzureClient, error) {
+
+	if azureConfig.GetStorageAccount() == nil || azureConfig.GetStorageAccessKey() == nil {
+		return nil, errors.New("Azure credentials of the dataset are not available")
+	}
+
+	accountName := *azureConfig.GetStorageAccount()
+
+	cred, err := azblob.NewSharedKeyCredential(accountName, *azureConfig.GetStorageAccessKey())
+	if err != nil {
+		return nil, err
+	}
+
+	options := &azblob.ClientOptions{}
+	if traffic != nil {
+		options.Transport = &trafficClient{client: http.DefaultClient, traffic: traffic}
+	}
+
+	service, err := azblob.NewClientWithSharedKeyCredential(fmt.Sprintf("https://%s.blob.core.windows.net/", accountName), cred, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AzureClient{ctx: ctx, service: service, container: container}, nil
+}
+
+func (r *AzureClient) ListObjects(prefix string) (objects []Object, err error) {
+
+	pager := r.service.NewListBlobsFlatPager(r.container, &azblob.ListBlobsFlatOptions{Prefix: &prefix})
+
+	for pager.More() {
+		page, err := pager.NextPage(r.ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range page.Segment.BlobItems {
+			object := Object{Key: *item.Name}
+			if p := item.Properties; p != nil {
+				if p.ContentLength != nil {
+					object.Size = *p.ContentLength
+				}
+				if p.ETag != nil {
+					object.ETag = string(*p.ETag)
+				}
+				if p.LastModified != nil {
+					object.LastModified = *p.LastModified
+				}
+				if len(p.ContentMD5) > 0 {
+					object.MD5 = hex.EncodeToString(p.ContentMD5)
+				}
+			}
+			// only set if blob versioning is enabled on the storage account
+			if item.VersionID != nil {
+				object.Version = *item.VersionID
+			}
+			objects = append(objects, object)
+		}
+	}
+
+	return objects, nil
+}
+
+func (r *AzureClient) UploadFile(input UploadFileInput) (eTag string, err error) {
+
+	file, err := os.Open(input.SrcAbsPath)
+	if err != nil {
+		return "", err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	options := &azblob.UploadFileOptions{}
+
+	if input.MD5 != "" {
+		checksum, err := hex.DecodeString(input.MD5)
+		if err != nil {
+			return "", err
+		}
+		options.HTTPHeaders = &blob.HTTPHeaders{BlobContentMD5: checksum}
+	}
+
+	response, err := r.service.UploadFile(r.ctx, r.container, input.RemoteObjectKey, file, options)
+	if err != nil {
+		return "", err
+	}
+
+	if response.ETag != nil {
+		eTag = string(*response.ETag)
+	}
+
+	return eTag, nil
+}
+
+func (r *AzureClient) DownloadFile(remoteObjectKey string, destAbsPath string) error {
+	return r.DownloadFileVersion(remoteObjectKey, "", destAbsPath)
+}
+
+func (r *AzureClient) DownloadFileVersion(remoteObjectKey string, version string, destAbsPath string) error {
+
+	blobClient := r.service.ServiceClient().NewContainerClient(r.container).NewBlobClient(remoteObjectKey)
+	if version != "" {
+		var err error
+		if blobClient, err = blobClient.WithVersionID(version); err != nil {
+			return err
+		}
+	}
+
+	response, err := blobClient.DownloadStream(r.ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(response.Body)
+
+	return writeFile(destAbsPath, response.Body)
+}
+
+func (r *AzureClient) DeleteObject(remoteObjectKey string) error {
+
+	_, err := r.service.DeleteBlob(r.ctx, r.container, remoteObjectKey, nil)
+
+	return err
+}
+
+func (r *AzureClient) CopyObject(src Object, destRemoteObjectKey string) error {
+
+	containerClient := r.service.ServiceClient().NewContainerClient(r.container)
+	srcBlob := containerClient.NewBlobClient(src.Key)
+	destBlob := containerClient.NewBlobClient(destRemoteObjectKey)
+
+	// copies within the same storage account are authorised by the shared key, and they may complete asynchronously
+	response, err := destBlob.StartCopyFromURL(r.ctx, srcBlob.URL(), nil)
+	if err != nil {
+		return err
+	}
+
+	status := response.CopyStatus
+	for status != nil && *status == blob.CopyStatusTypePending {
+		time.Sleep(time.Second)
+
+		properties, err := destBlob.GetProperties(r.ctx, nil)
+		if err != nil {
+			return err
+		}
+		status = properties.CopyStatus
+	}
+
+	if status != nil && *status != blob.CopyStatusTypeSuccess {
+		return errors.New(fmt.Sprintf("copying %s to %s ended with status %s", src.Key, destRemoteObjectKey, *status))
+	}
+
+	return nil
+}
+
+func (r *AzureClient) OpenObject(remoteObjectKey string) (io.ReadCloser, error) {
+	return r.OpenObjectRange(remoteObjectKey, 0, -1)
+}
+
+func (r *AzureClient) OpenObjectRange(remoteObjectKey string, offset int64, length int64) (io.ReadCloser, error) {
+
+	// a count of 0 reads to the end of the blob
+	byteRange := blob.HTTPRange{Offset: offset}
+	if length >= 0 {
+		byteRange.Count = length
+	}
+
+	response, err := r.service.DownloadStream(r.ctx, r.container, remoteObjectKey, &azblob.DownloadStreamOptions{Range: byteRange})
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Body, nil
+}
+
+func (r *AzureClient) UploadStream(remoteObjectKey string, reader io.Reader, size int64) (eTag string, err error) {
+
+	response, err := r.service.UploadStream(r.ctx, r.container, remoteObjectKey, reader, nil)
+	if err != nil {
+		return "", err
+	}
+
+	if response.ETag != nil {
+		eTag = string(*response.ETag)
+	}
+
+	return eTag, nil
+}
+
+func (r *AzureClient) blockBlobClient(remoteObjectKey string) *blockblob.Client {
+	return r.service.ServiceClient().NewContainerClient(r.container).NewBlockBlobClient(remoteObjectKey)
+}
+
+// blockID returns the id of the block that holds a part, block ids of a blob must all have the same length.
+func blockID(uploadId string, partNumber int) string {
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s-%05d", uploadId, partNumber)))
+}
+
+// CreateMultipartUpload returns a random id for the blocks of the upload,
+// as uncommitted blocks of a blob are kept by Azure without an explicit upload.
+func (r *AzureClient) CreateMultipartUpload(remoteObjectKey string) (uploadId string, err error) {
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(id), nil
+}
+
+func (r *AzureClient) UploadPart(remoteObjectKey string, uploadId string, partNumber int, reader io.ReadSeeker, size int64) (eTag string, err error) {
+
+	_, err = r.blockBlobClient(remoteObjectKey).StageBlock(r.ctx, blockID(uploadId, partNumber), streaming.NopCloser(reader), nil)
+
+	// blocks have no ETag, they are identified by their block ids
+	return "", err
+}
+
+func (r *AzureClient) CompleteMultipartUpload(remoteObjectKey string, uploadId string, parts []Part, md5 string) (eTag string, err error) {
+
+	blockIDs := make([]string, len(parts))
+	for i, p := range parts {
+		blockIDs[i] = blockID(uploadId, p.Number)
+	}
+
+	options := &blockblob.CommitBlockListOptions{}
+
+	if md5 != "" {
+		checksum, err := hex.DecodeString(md5)
+		if err != nil {
+			return "", err
+		}
+		options.HTTPHeaders = &blob.HTTPHeaders{BlobContentMD5: checksum}
+	}
+
+	response, err := r.blockBlobClient(remoteObjectKey).CommitBlockList(r.ctx, blockIDs, options)
+	if err != nil {
+		return "", err
+	}
+
+	if response.ETag != nil {
+		eTag = string(*response.ETag)
+	}
+
+	return eTag, nil
+}
+
+// AbortMultipartUpload does nothing, as Azure discards uncommitted blocks by itself after a week.
+func (r *AzureClient) AbortMultipartUpload(remoteObjectKey string, uploadId string) error {
+	return nil
+}
@@ -0,0 +1,160 @@
+package data_storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/deploifai/sdk-go/api"
+	"github.com/deploifai/sdk-go/api/generated"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Object is an object stored in the container of a data storage.
+type Object struct {
+	Key          string
+	Size         int64
+	ETag         string
+	LastModified time.Time
+
+	// MD5 is the hex encoded MD5 checksum of the object content.
+	// It is empty if the cloud provider does not report one for the object.
+	MD5 string
+
+	// Version identifies this version of the object, if the cloud provider keeps versions of objects.
+	// It is empty if the cloud provider does not report one when listing objects.
+	Version string
+
+	// Generation changes every time the object is written, on cloud providers that number the writes of objects
+	// even if they do not keep the versions, e.g. Google Cloud Storage. It is empty otherwise.
+	Generation string
+}
+
+type UploadFileInput struct {
+	SrcAbsPath      string
+	RemoteObjectKey string
+
+	// MD5 is the hex encoded MD5 checksum of the file, which is optional.
+	// If set, the cloud provider stores it with the object and rejects the upload if the content does not match.
+	MD5 string
+}
+
+// VersionLister is implemented by the clients of cloud providers that only report the versions of objects
+// with a separate listing, which is slower than ListObjects, so it is only used to create snapshots.
+type VersionLister interface {
+	// ListObjectVersions lists all objects whose keys start with prefix like ListObjects, with their versions.
+	ListObjectVersions(prefix string) ([]Object, error)
+}
+
+// ErrCopyUnsupported is returned by Client.CopyObject when the cloud provider cannot copy an object on its side,
+// in which case the object has to be streamed through the client instead.
+var ErrCopyUnsupported = errors.New("the cloud provider cannot copy this object on its side")
+
+// Client is a client to the container of a data storage on the cloud provider that backs it.
+type Client interface {
+	// ListObjects lists all objects whose keys start with prefix.
+	ListObjects(prefix string) ([]Object, error)
+	// UploadFile uploads a local file to an object, replacing the object if it exists.
+	// It returns the ETag of the new object.
+	UploadFile(input UploadFileInput) (eTag string, err error)
+	// DownloadFile downloads an object to a local file, replacing the file if it exists.
+	DownloadFile(remoteObjectKey string, destAbsPath string) error
+	// DownloadFileVersion downloads a version of an object to a local file like DownloadFile.
+	// If version is empty, the current version is downloaded.
+	DownloadFileVersion(remoteObjectKey string, version string, destAbsPath string) error
+	// DeleteObject deletes an object.
+	DeleteObject(remoteObjectKey string) error
+	// CopyObject copies an object to another key in the same container, on the cloud provider's side.
+	// It returns ErrCopyUnsupported if the cloud provider cannot copy the object that way.
+	CopyObject(src Object, destRemoteObjectKey string) error
+	// OpenObject opens an object for reading, the caller must close it.
+	OpenObject(remoteObjectKey string) (io.ReadCloser, error)
+	// OpenObjectRange opens length bytes of an object from offset for reading like OpenObject.
+	// If length is -1, the object is read to its end.
+	OpenObjectRange(remoteObjectKey string, offset int64, length int64) (io.ReadCloser, error)
+	// UploadStream uploads size bytes read from reader to an object, replacing the object if it exists.
+	// If size is -1, reader is read to its end, which only clients that do not implement MultipartClient support.
+	// It returns the ETag of the new object.
+	UploadStream(remoteObjectKey string, reader io.Reader, size int64) (eTag string, err error)
+}
+
+// New creates a Client for the data storage with the given id,
+// using the cloud credentials that Deploifai manages for that data storage.
+// If traffic is not nil, it counts the bytes the client transfers, and limits their bandwidth if it has a limit.
+func New(ctx context.Context, api api.Provider, dataStorageId string, traffic *Traffic) (Client, error) {
+
+	data, err := api.GetGQLClient().GetDataStorage(ctx, generated.DataStorageWhereUniqueInput{ID: &dataStorageId})
+	if err != nil {
+		return nil, api.ProcessGQLError(err)
+	}
+
+	dataStorage := data.GetDataStorage()
+	if len(dataStorage.GetContainers()) == 0 || dataStorage.GetContainers()[0].GetCloudName() == nil {
+		return nil, errors.New(fmt.Sprintf("dataset %s has no storage container", dataStorage.GetName()))
+	}
+
+	container := *dataStorage.GetContainers()[0].GetCloudName()
+	yodaConfig := dataStorage.GetCloudProviderYodaConfig()
+
+	switch provider := *dataStorage.GetCloudProfile().GetProvider(); provider {
+	case generated.CloudProviderAws:
+		return NewAWSClient(ctx, yodaConfig.GetAwsConfig(), container, traffic)
+	case generated.CloudProviderAzure:
+		return NewAzureClient(ctx, yodaConfig.GetAzureConfig(), container, traffic)
+	case generated.CloudProviderGcp:
+		return NewGCPClient(ctx, yodaConfig.GetGcpConfig(), container, traffic)
+	default:
+		return nil, errors.New(fmt.Sprintf("cloud provider %s is not supported for datasets", provider))
+	}
+}
+
+// DownloadTempSuffix ends the names of the temporary files that downloads are written to,
+// before they are renamed into place.
+const DownloadTempSuffix = ".deploifai-download"
+
+// writeFile writes the content of reader to a file, creating its parent directories if needed.
+// The content is written to a temporary file in the same directory, which replaces the file only once it is complete,
+// so that an interrupted download never leaves a partial file behind.
+func writeFile(path string, reader io.Reader) error {
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	// keep the permissions of the file being replaced
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	file, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*"+DownloadTempSuffix)
+	if err != nil {
+		return err
+	}
+	tmpPath := file.Name()
+
+	if _, err = io.Copy(file, reader); err != nil {
+		_ = file.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err = file.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	if err = os.Chmod(tmpPath, perm); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	if err = os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	return nil
+}
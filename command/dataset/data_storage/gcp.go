@@ -0,0 +1,197 @@
+package data_storage
+
+import (
+	"cloud.google.com/go/storage"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/deploifai/sdk-go/api/generated"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// GCPClient is a Client for a data storage backed by a Google Cloud Storage bucket.
+type GCPClient struct {
+	ctx     context.Context
+	service *storage.Client
+	bucket  string
+
+	// versioning is whether the bucket keeps the generations of objects after they change, which is looked up once
+	versioning     bool
+	versioningOnce sync.Once
+}
+
+func NewGCPClient(ctx context.Context, gcpConfig *generated.GCPYodaConfigFragment, bucket string, traffic *Traffic) (*GCPClient, error) {
+
+	if gcpConfig.GetGcpServiceAccountKey() == nil {
+		return nil, errors.New("GCP credentials of the dataset are not available")
+	}
+
+	opts := []option.ClientOption{option.WithCredentialsJSON([]byte(*gcpConfig.GetGcpServiceAccountKey()))}
+
+	if traffic != nil {
+		// traffic is counted on top of the authenticated transport that the storage client would otherwise create
+		httpClient, _, err := htransport.NewClient(ctx, append(opts, option.WithScopes(storage.ScopeFullControl))...)
+		if err != nil {
+			return nil, err
+		}
+		httpClient.Transport = &trafficTransport{base: httpClient.Transport, traffic: traffic}
+		opts = []option.ClientOption{option.WithHTTPClient(httpClient)}
+	}
+
+	service, err := storage.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GCPClient{ctx: ctx, service: service, bucket: bucket}, nil
+}
+
+// ListObjects lists the objects with their generations, which are also their versions if the bucket has versioning enabled.
+func (r *GCPClient) ListObjects(prefix string) (objects []Object, err error) {
+
+	versioning := r.versioningEnabled()
+
+	it := r.service.Bucket(r.bucket).Objects(r.ctx, &storage.Query{Prefix: prefix})
+
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		object := Object{
+			Key:          attrs.Name,
+			Size:         attrs.Size,
+			ETag:         attrs.Etag,
+			LastModified: attrs.Updated,
+			MD5:          hex.EncodeToString(attrs.MD5),
+			Generation:   strconv.FormatInt(attrs.Generation, 10),
+		}
+		// without versioning, an older generation is gone once the object changes, so it is no version to go back to
+		if versioning {
+			object.Version = object.Generation
+		}
+		objects = append(objects, object)
+	}
+
+	return objects, nil
+}
+
+// versioningEnabled reports whether the bucket has versioning enabled.
+// If the bucket cannot be read, it is taken not to, so that changed objects are detected instead of restored.
+func (r *GCPClient) versioningEnabled() bool {
+
+	r.versioningOnce.Do(func() {
+		if attrs, err := r.service.Bucket(r.bucket).Attrs(r.ctx); err == nil {
+			r.versioning = attrs.VersioningEnabled
+		}
+	})
+
+	return r.versioning
+}
+
+func (r *GCPClient) UploadFile(input UploadFileInput) (eTag string, err error) {
+
+	file, err := os.Open(input.SrcAbsPath)
+	if err != nil {
+		return "", err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	writer := r.service.Bucket(r.bucket).Object(input.RemoteObjectKey).NewWriter(r.ctx)
+
+	if input.MD5 != "" {
+		if writer.MD5, err = hex.DecodeString(input.MD5); err != nil {
+			return "", err
+		}
+	}
+
+	if _, err := io.Copy(writer, file); err != nil {
+		_ = writer.Close()
+		return "", err
+	}
+
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	return writer.Attrs().Etag, nil
+}
+
+func (r *GCPClient) DownloadFile(remoteObjectKey string, destAbsPath string) error {
+	return r.DownloadFileVersion(remoteObjectKey, "", destAbsPath)
+}
+
+func (r *GCPClient) DownloadFileVersion(remoteObjectKey string, version string, destAbsPath string) error {
+
+	object := r.service.Bucket(r.bucket).Object(remoteObjectKey)
+
+	// versions of objects are generations, which are kept after the object changes if the bucket has versioning enabled
+	if version != "" {
+		generation, err := strconv.ParseInt(version, 10, 64)
+		if err != nil {
+			return errors.New(fmt.Sprintf("invalid version of %s: %s", remoteObjectKey, version))
+		}
+		object = object.Generation(generation)
+	}
+
+	reader, err := object.NewReader(r.ctx)
+	if err != nil {
+		return err
+	}
+	defer func(reader *storage.Reader) {
+		_ = reader.Close()
+	}(reader)
+
+	return writeFile(destAbsPath, reader)
+}
+
+func (r *GCPClient) DeleteObject(remoteObjectKey string) error {
+	return r.service.Bucket(r.bucket).Object(remoteObjectKey).Delete(r.ctx)
+}
+
+func (r *GCPClient) CopyObject(src Object, destRemoteObjectKey string) error {
+
+	bucket := r.service.Bucket(r.bucket)
+
+	// the copier rewrites large objects in several requests until the copy is done
+	_, err := bucket.Object(destRemoteObjectKey).CopierFrom(bucket.Object(src.Key)).Run(r.ctx)
+
+	return err
+}
+
+func (r *GCPClient) OpenObject(remoteObjectKey string) (io.ReadCloser, error) {
+	return r.OpenObjectRange(remoteObjectKey, 0, -1)
+}
+
+func (r *GCPClient) OpenObjectRange(remoteObjectKey string, offset int64, length int64) (io.ReadCloser, error) {
+	return r.service.Bucket(r.bucket).Object(remoteObjectKey).NewRangeReader(r.ctx, offset, length)
+}
+
+func (r *GCPClient) UploadStream(remoteObjectKey string, reader io.Reader, size int64) (eTag string, err error) {
+
+	writer := r.service.Bucket(r.bucket).Object(remoteObjectKey).NewWriter(r.ctx)
+
+	if _, err := io.Copy(writer, reader); err != nil {
+		_ = writer.Close()
+		return "", err
+	}
+
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	return writer.Attrs().Etag, nil
+}
@@ -0,0 +1,26 @@
+package data_storage
+
+import "io"
+
+// Part is a part of an object that was uploaded in parts.
+type Part struct {
+	Number int
+	ETag   string
+}
+
+// MultipartClient is implemented by the clients of cloud providers that can upload a large object in parts,
+// where an upload that was interrupted can continue with the parts that were not uploaded yet.
+type MultipartClient interface {
+	Client
+
+	// CreateMultipartUpload starts uploading an object in parts, and returns the id of the upload.
+	CreateMultipartUpload(remoteObjectKey string) (uploadId string, err error)
+	// UploadPart uploads size bytes read from reader as the part with the given number, starting from 1.
+	UploadPart(remoteObjectKey string, uploadId string, partNumber int, reader io.ReadSeeker, size int64) (eTag string, err error)
+	// CompleteMultipartUpload assembles the parts into the object, replacing the object if it exists.
+	// md5 is the hex encoded MD5 checksum of the whole object, which is optional.
+	// It returns the ETag of the new object.
+	CompleteMultipartUpload(remoteObjectKey string, uploadId string, parts []Part, md5 string) (eTag string, err error)
+	// AbortMultipartUpload discards the parts that were uploaded.
+	AbortMultipartUpload(remoteObjectKey string, uploadId string) error
+}
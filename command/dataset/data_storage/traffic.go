@@ -0,0 +1,153 @@
+package data_storage
+
+import (
+	"io"
+	"math"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxLimitedReadSize is the most bytes a limited reader reads at once, so that the bandwidth is shared smoothly.
+const maxLimitedReadSize = 64 * 1024
+
+// Traffic counts the bytes that clients send and receive in the bodies of their requests,
+// which gives the progress of the transfers that share it, and optionally limits their bandwidth.
+type Traffic struct {
+	bytes atomic.Int64
+
+	// limiter is nil if the bandwidth is unlimited
+	limiter *limiter
+}
+
+// NewTraffic creates a Traffic that limits the bandwidth to bytesPerSecond, or does not limit it if bytesPerSecond is 0.
+func NewTraffic(bytesPerSecond int64) *Traffic {
+
+	t := &Traffic{}
+	if bytesPerSecond > 0 {
+		t.limiter = &limiter{rate: float64(bytesPerSecond), tokens: float64(bytesPerSecond), last: time.Now()}
+	}
+
+	return t
+}
+
+// Bytes returns the number of bytes sent and received so far.
+func (t *Traffic) Bytes() int64 {
+	return t.bytes.Load()
+}
+
+func (t *Traffic) transferred(n int) {
+	t.bytes.Add(int64(n))
+	if t.limiter != nil {
+		t.limiter.wait(n)
+	}
+}
+
+// limiter is a token bucket that limits the bandwidth of all the transfers that share it.
+// It holds at most a second's worth of bytes, so transfers cannot burst above the limit for long after being idle.
+type limiter struct {
+	// bytes per second
+	rate   float64
+	tokens float64
+	last   time.Time
+	mutex  sync.Mutex
+}
+
+// wait takes n bytes from the bucket, and blocks until the bucket has refilled enough to cover them.
+// Callers that take more than there is go into debt, which makes the callers after them wait in turn.
+func (l *limiter) wait(n int) {
+
+	l.mutex.Lock()
+
+	now := time.Now()
+	l.tokens = math.Min(l.rate, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens -= float64(n)
+
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+
+	l.mutex.Unlock()
+
+	time.Sleep(delay)
+}
+
+type trafficReadCloser struct {
+	body    io.ReadCloser
+	traffic *Traffic
+}
+
+func (r *trafficReadCloser) Read(p []byte) (int, error) {
+	if r.traffic.limiter != nil && len(p) > maxLimitedReadSize {
+		p = p[:maxLimitedReadSize]
+	}
+	n, err := r.body.Read(p)
+	r.traffic.transferred(n)
+	return n, err
+}
+
+func (r *trafficReadCloser) Close() error {
+	return r.body.Close()
+}
+
+// wrapRequest returns a copy of req whose body is counted, and read no faster than the limit if there is one.
+func (t *Traffic) wrapRequest(req *http.Request) *http.Request {
+
+	if req.Body == nil || req.Body == http.NoBody {
+		return req
+	}
+
+	counted := req.Clone(req.Context())
+	counted.Body = &trafficReadCloser{body: req.Body, traffic: t}
+
+	if req.GetBody != nil {
+		counted.GetBody = func() (io.ReadCloser, error) {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			return &trafficReadCloser{body: body, traffic: t}, nil
+		}
+	}
+
+	return counted
+}
+
+// wrapResponse makes the body of resp counted, and read no faster than the limit if there is one.
+func (t *Traffic) wrapResponse(resp *http.Response, err error) (*http.Response, error) {
+
+	if err != nil || resp.Body == nil {
+		return resp, err
+	}
+
+	resp.Body = &trafficReadCloser{body: resp.Body, traffic: t}
+
+	return resp, nil
+}
+
+type httpDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// trafficClient is an HTTP client whose requests and responses are counted by a Traffic.
+type trafficClient struct {
+	client  httpDoer
+	traffic *Traffic
+}
+
+func (r *trafficClient) Do(req *http.Request) (*http.Response, error) {
+	return r.traffic.wrapResponse(r.client.Do(r.traffic.wrapRequest(req)))
+}
+
+// trafficTransport is an HTTP transport whose requests and responses are counted by a Traffic.
+type trafficTransport struct {
+	base    http.RoundTripper
+	traffic *Traffic
+}
+
+func (r *trafficTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r.traffic.wrapResponse(r.base.RoundTrip(r.traffic.wrapRequest(req)))
+}
@@ -0,0 +1,80 @@
+package data_storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterWait(t *testing.T) {
+	tests := []struct {
+		name string
+		// bytes per second, and how many bytes the bucket holds when the first wait starts
+		rate   float64
+		tokens float64
+		waits  []int
+		// the shortest time the waits should take, and the longest, which leaves room for a slow machine
+		min time.Duration
+		max time.Duration
+	}{
+		{name: "within the bucket", rate: 1000, tokens: 1000, waits: []int{400, 600}, min: 0, max: 50 * time.Millisecond},
+		{name: "beyond the bucket", rate: 1000, tokens: 1000, waits: []int{1000, 100}, min: 100 * time.Millisecond, max: 300 * time.Millisecond},
+		{name: "empty bucket", rate: 1000, tokens: 0, waits: []int{200}, min: 200 * time.Millisecond, max: 400 * time.Millisecond},
+		{name: "debt is paid by the next wait", rate: 1000, tokens: 0, waits: []int{100, 100}, min: 200 * time.Millisecond, max: 400 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &limiter{rate: tt.rate, tokens: tt.tokens, last: time.Now()}
+
+			start := time.Now()
+			for _, n := range tt.waits {
+				l.wait(n)
+			}
+			elapsed := time.Since(start)
+
+			if elapsed < tt.min || elapsed > tt.max {
+				t.Errorf("waits took %s, want between %s and %s", elapsed, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestLimiterRefillIsCapped(t *testing.T) {
+	// a bucket that has been idle for longer than a second holds no more than a second's worth of bytes
+	l := &limiter{rate: 1000, tokens: 0, last: time.Now().Add(-time.Hour)}
+
+	start := time.Now()
+	l.wait(1100)
+	elapsed := time.Since(start)
+
+	if elapsed < 100*time.Millisecond || elapsed > 300*time.Millisecond {
+		t.Errorf("wait took %s, want about 100ms", elapsed)
+	}
+}
+
+func TestTrafficBytes(t *testing.T) {
+	tests := []struct {
+		name           string
+		bytesPerSecond int64
+		limited        bool
+	}{
+		{name: "unlimited", bytesPerSecond: 0, limited: false},
+		{name: "limited", bytesPerSecond: 1 << 20, limited: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			traffic := NewTraffic(tt.bytesPerSecond)
+			if (traffic.limiter != nil) != tt.limited {
+				t.Fatalf("NewTraffic(%d) has a limiter: %v, want %v", tt.bytesPerSecond, traffic.limiter != nil, tt.limited)
+			}
+
+			traffic.transferred(100)
+			traffic.transferred(23)
+
+			if traffic.Bytes() != 123 {
+				t.Errorf("Bytes() = %d, want 123", traffic.Bytes())
+			}
+		})
+	}
+}
@@ -7,24 +7,37 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var datasetFlag string
+
 // Cmd represents the dataset command
 var Cmd = &cobra.Command{
 	Use:   "dataset",
 	Short: "Manage, and interact with datasets",
-	Long: `Initialize, push, or pull datasets in the current workspace.
+	Long: `Create, list, initialize, push, pull, or sync datasets in the current workspace, show their status, or manage the files in them.
 
 A dataset refers to a collection of files that are stored in a remote object storage on the cloud.
+
+Commands use the dataset whose directory contains the current directory, the innermost one if dataset directories are nested.
+Use --dataset with the name or id of a dataset to use it from anywhere in the project.
+
+Transfers stop when they are interrupted with Ctrl-C, after cancelling the files in flight, and report the files that finished.
+Files are downloaded to temporary files that only replace the local files once they are complete.
 `,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// the context is cancelled on Ctrl-C, which stops transfers
+		transferContext = cmd.Context()
+	},
 }
 
 func init() {
-	Cmd.AddCommand(initCmd, pushCmd, pullCmd)
+	Cmd.AddCommand(initCmd, createCmd, listCmd, unlinkCmd, relinkCmd, pushCmd, pullCmd, syncCmd, snapshotCmd, snapshotsCmd, checkoutCmd, pinCmd, restoreCmd, verifyCmd, statusCmd, lsCmd, duCmd, catCmd, putCmd, rmCmd, mvCmd, cpCmd)
 
 	// Here you will define your flags and configuration settings.
 
 	// Cobra supports Persistent Flags which will work for this command
 	// and all subcommands, e.g.:
 	// Cmd.PersistentFlags().String("foo", "", "A help for foo")
+	Cmd.PersistentFlags().StringVar(&datasetFlag, "dataset", "", "name or id of the dataset to use, instead of the one of the current directory")
 
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
@@ -0,0 +1,202 @@
+/*
+Copyright © 2023 Sean Chok
+*/
+package dataset
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/deploifai/cli-go/command/ctx"
+	"github.com/deploifai/cli-go/command/dataset/data_storage"
+	"github.com/deploifai/sdk-go/service/dataset"
+	"github.com/spf13/cobra"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+var duDepth int
+var duJSON bool
+
+// duEntry is the size of the files under a directory of a dataset, in the dataset and in the local directory.
+type duEntry struct {
+	Path       string `json:"path"`
+	Key        string `json:"key"`
+	Size       int64  `json:"size"`
+	Files      int    `json:"files"`
+	LocalSize  int64  `json:"localSize"`
+	LocalFiles int    `json:"localFiles"`
+}
+
+// duCmd represents the du command
+var duCmd = &cobra.Command{
+	Use:   "du [<path>]",
+	Short: "Show how much storage the directories of a dataset use",
+	Long: `Show the size and number of the files under each directory of a dataset, as they are stored on the cloud,
+largest first, next to the size and number of the files in the same local directory,
+so that files that have not been pushed or pulled stand out.
+The last line is the total of <path>.
+
+<path> is resolved like the paths of "deploifai dataset pull", relative to the current directory,
+and does not have to exist locally. If no <path> is specified, the current directory is used.
+Local files that are ignored by .deploifaiignore are not counted.
+
+Use --depth to show directories further down than the ones directly under <path>, or 0 to only show the total.
+`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		_context := ctx.GetContextValue(cmd)
+
+		if duDepth < 0 {
+			return errors.New(fmt.Sprintf("invalid depth: %d, it must be at least 0", duDepth))
+		}
+
+		// get the dataset and directory path from config
+		ok, ds, datasetDirPath, err := getTargetDataset(cmd)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("the current directory is not initialised as a dataset, use --dataset to choose one")
+		}
+
+		absPaths, err := getAbsPaths(datasetDirPath, args)
+		if err != nil {
+			return err
+		}
+
+		// verify the path, which does not have to exist locally
+		if ok, invalidArgs, err := verifyPullPaths(datasetDirPath, args, absPaths); err != nil {
+			return err
+		} else if !ok {
+			return errors.New(fmt.Sprintf("invalid path: %s", strings.Join(invalidArgs, ", ")))
+		}
+
+		remoteObjectPrefixes, err := getRemoteObjectPrefixes(datasetDirPath, absPaths)
+		if err != nil {
+			return err
+		}
+		remoteObjectPrefix := remoteObjectPrefixes[0]
+
+		client, err := data_storage.New(cmd.Context(), _context.ServiceClientConfig.API, ds.ID, nil)
+		if err != nil {
+			return err
+		}
+
+		remoteObjects, err := listRemoteObjects(client, remoteObjectPrefix)
+		if err != nil {
+			return err
+		}
+
+		localObjects, err := listLocalObjects(newIgnoreMatcher(datasetDirPath), datasetDirPath, getDatasetDirs(*_context.Project), absPaths[0])
+		if err != nil {
+			return err
+		}
+
+		if len(remoteObjects) == 0 && len(localObjects) == 0 && len(args) > 0 {
+			return errors.New(fmt.Sprintf("no files found at %s", args[0]))
+		}
+
+		totalPath := "."
+		if len(args) > 0 {
+			totalPath = args[0]
+		}
+
+		entries := sumDirSizes(remoteObjectPrefix, totalPath, remoteObjects, localObjects, duDepth)
+
+		if duJSON {
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+
+			return encoder.Encode(entries)
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+
+		_, _ = fmt.Fprintln(w, "SIZE\tFILES\tLOCAL SIZE\tLOCAL FILES\tPATH")
+		for _, e := range entries {
+			_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%s\n", formatBytes(e.Size), e.Files, formatBytes(e.LocalSize), e.LocalFiles, e.Path)
+		}
+
+		return w.Flush()
+	},
+}
+
+func init() {
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// duCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// duCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	duCmd.Flags().IntVarP(&duDepth, "depth", "d", 1, "how many levels of directories under the path to show")
+	duCmd.Flags().BoolVar(&duJSON, "json", false, "print the sizes as JSON, in bytes")
+}
+
+// sumDirSizes adds up the sizes of the remote and local files under remoteObjectPrefix for every directory down to depth,
+// named relative to the prefix. The directories are sorted by remote size, largest first,
+// and followed by the total of the prefix, named totalPath.
+func sumDirSizes(remoteObjectPrefix string, totalPath string, remoteObjects map[string]data_storage.Object, localObjects map[string]localObject, depth int) []duEntry {
+
+	prefix := dataset.CleanRemoteObjectPrefix(remoteObjectPrefix)
+
+	total := &duEntry{Path: totalPath, Key: prefix}
+	dirs := map[string]*duEntry{}
+
+	// add returns the entries of the directories that key is counted in, the total and its ancestors down to depth
+	add := func(key string) []*duEntry {
+		entries := []*duEntry{total}
+		if key == strings.TrimSuffix(prefix, "/") {
+			// the prefix is a single file
+			total.Key = key
+			return entries
+		}
+
+		rel := strings.TrimPrefix(key, prefix)
+		parts := strings.Split(rel, "/")
+		for i := 1; i < len(parts) && i <= depth; i++ {
+			relDir := strings.Join(parts[:i], "/") + "/"
+			e, ok := dirs[relDir]
+			if !ok {
+				e = &duEntry{Path: path.Join(totalPath, relDir) + "/", Key: prefix + relDir}
+				dirs[relDir] = e
+			}
+			entries = append(entries, e)
+		}
+
+		return entries
+	}
+
+	for key, r := range remoteObjects {
+		for _, e := range add(key) {
+			e.Size += r.Size
+			e.Files++
+		}
+	}
+	for key, l := range localObjects {
+		for _, e := range add(key) {
+			e.LocalSize += l.Size
+			e.LocalFiles++
+		}
+	}
+
+	entries := make([]duEntry, 0, len(dirs)+1)
+	for _, e := range dirs {
+		entries = append(entries, *e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Size != entries[j].Size {
+			return entries[i].Size > entries[j].Size
+		}
+		return entries[i].Key < entries[j].Key
+	})
+
+	return append(entries, *total)
+}
@@ -0,0 +1,110 @@
+package dataset
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/deploifai/cli-go/command/dataset/data_storage"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fakeClient is a data_storage.Client that keeps objects in memory.
+type fakeClient struct {
+	objects map[string][]byte
+	// number of files uploaded
+	uploads int
+	// sizes passed to UploadStream
+	streamSizes []int64
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{objects: map[string][]byte{}}
+}
+
+func (f *fakeClient) ListObjects(prefix string) ([]data_storage.Object, error) {
+
+	var objects []data_storage.Object
+	for key, content := range f.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, data_storage.Object{Key: key, Size: int64(len(content)), ETag: `"` + testMD5(string(content)) + `"`, MD5: testMD5(string(content))})
+		}
+	}
+
+	return objects, nil
+}
+
+func (f *fakeClient) UploadFile(input data_storage.UploadFileInput) (string, error) {
+
+	content, err := os.ReadFile(input.SrcAbsPath)
+	if err != nil {
+		return "", err
+	}
+	f.objects[input.RemoteObjectKey] = content
+	f.uploads++
+
+	return `"` + testMD5(string(content)) + `"`, nil
+}
+
+func (f *fakeClient) DownloadFile(remoteObjectKey string, destAbsPath string) error {
+
+	content, ok := f.objects[remoteObjectKey]
+	if !ok {
+		return os.ErrNotExist
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destAbsPath), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(destAbsPath, content, 0644)
+}
+
+func (f *fakeClient) DownloadFileVersion(remoteObjectKey string, version string, destAbsPath string) error {
+	return f.DownloadFile(remoteObjectKey, destAbsPath)
+}
+
+func (f *fakeClient) DeleteObject(remoteObjectKey string) error {
+	delete(f.objects, remoteObjectKey)
+	return nil
+}
+
+func (f *fakeClient) CopyObject(src data_storage.Object, destRemoteObjectKey string) error {
+	return data_storage.ErrCopyUnsupported
+}
+
+func (f *fakeClient) OpenObject(remoteObjectKey string) (io.ReadCloser, error) {
+	return f.OpenObjectRange(remoteObjectKey, 0, -1)
+}
+
+func (f *fakeClient) OpenObjectRange(remoteObjectKey string, offset int64, length int64) (io.ReadCloser, error) {
+
+	content, ok := f.objects[remoteObjectKey]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	content = content[offset:]
+	if length >= 0 {
+		content = content[:length]
+	}
+
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (f *fakeClient) UploadStream(remoteObjectKey string, reader io.Reader, size int64) (string, error) {
+
+	f.streamSizes = append(f.streamSizes, size)
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+	if size >= 0 && int64(len(content)) != size {
+		return "", errors.New(fmt.Sprintf("read %d bytes, want %d", len(content), size))
+	}
+	f.objects[remoteObjectKey] = content
+
+	return `"` + testMD5(string(content)) + `"`, nil
+}
@@ -0,0 +1,62 @@
+package dataset
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// pathFilter selects paths in a dataset with --include and --exclude glob patterns.
+// Patterns are matched against paths relative to the dataset directory, and "**" matches any number of directories.
+type pathFilter struct {
+	include []string
+	exclude []string
+}
+
+func newPathFilter(include []string, exclude []string) (*pathFilter, error) {
+
+	for _, pattern := range append(append([]string{}, include...), exclude...) {
+		if !doublestar.ValidatePattern(pattern) {
+			return nil, errors.New(fmt.Sprintf("invalid glob pattern: %s", pattern))
+		}
+	}
+
+	return &pathFilter{include: include, exclude: exclude}, nil
+}
+
+// matches reports whether key is selected, which is when it matches any include pattern, or there are none,
+// and it matches no exclude pattern.
+func (r *pathFilter) matches(key string) bool {
+
+	included := len(r.include) == 0
+	for _, pattern := range r.include {
+		if ok, _ := doublestar.Match(pattern, key); ok {
+			included = true
+			break
+		}
+	}
+	if !included {
+		return false
+	}
+
+	for _, pattern := range r.exclude {
+		if ok, _ := doublestar.Match(pattern, key); ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// filterObjects returns the local files or remote objects whose keys filter selects.
+func filterObjects[T any](filter *pathFilter, objects map[string]T) map[string]T {
+
+	filtered := map[string]T{}
+	for key, o := range objects {
+		if filter.matches(key) {
+			filtered[key] = o
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,177 @@
+package dataset
+
+import (
+	"bufio"
+	"errors"
+	"github.com/bmatcuk/doublestar/v4"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreFilename is the name of the files that list the paths in a dataset directory that should not be pushed.
+// They use the same syntax as .gitignore files, and apply to the directory they are in and its subdirectories.
+const ignoreFilename = ".deploifaiignore"
+
+type ignoreRule struct {
+	pattern string
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreMatcher matches paths in a dataset directory against the rules of the .deploifaiignore files in it.
+// Paths are given as remote object keys, relative to the dataset directory.
+type ignoreMatcher struct {
+	datasetDirPath string
+
+	// rules of the .deploifaiignore file in each directory, loaded when first needed
+	rules map[string][]ignoreRule
+	// whether each directory is ignored, for directories that have been matched
+	ignoredDirs map[string]bool
+}
+
+func newIgnoreMatcher(datasetDirPath string) *ignoreMatcher {
+	return &ignoreMatcher{
+		datasetDirPath: datasetDirPath,
+		rules:          map[string][]ignoreRule{},
+		ignoredDirs:    map[string]bool{},
+	}
+}
+
+// isIgnored reports whether the file or directory at key is ignored.
+// Like git, a path inside an ignored directory is always ignored, even if a rule would re-include it.
+func (r *ignoreMatcher) isIgnored(key string, isDir bool) (bool, error) {
+
+	if key == "." || key == "" {
+		return false, nil
+	}
+
+	if isDir {
+		if ignored, ok := r.ignoredDirs[key]; ok {
+			return ignored, nil
+		}
+	}
+
+	parent := path.Dir(key)
+	if parent != "." {
+		if ignored, err := r.isIgnored(parent, true); err != nil || ignored {
+			return ignored, err
+		}
+	}
+
+	// rules in deeper directories take precedence, and later rules in the same file take precedence
+	ignored := false
+	for _, dir := range ancestorDirs(key) {
+		rules, err := r.loadRules(dir)
+		if err != nil {
+			return false, err
+		}
+
+		rel := key
+		if dir != "" {
+			rel = key[len(dir)+1:]
+		}
+
+		for _, rule := range rules {
+			if rule.dirOnly && !isDir {
+				continue
+			}
+			if ok, _ := doublestar.Match(rule.pattern, rel); ok {
+				ignored = !rule.negate
+			}
+		}
+	}
+
+	if isDir {
+		r.ignoredDirs[key] = ignored
+	}
+
+	return ignored, nil
+}
+
+func (r *ignoreMatcher) loadRules(dir string) ([]ignoreRule, error) {
+
+	if rules, ok := r.rules[dir]; ok {
+		return rules, nil
+	}
+
+	rules, err := readIgnoreFile(filepath.Join(r.datasetDirPath, filepath.FromSlash(dir), ignoreFilename))
+	if err != nil {
+		return nil, err
+	}
+
+	r.rules[dir] = rules
+
+	return rules, nil
+}
+
+// readIgnoreFile parses a .deploifaiignore file. A missing file has no rules.
+func readIgnoreFile(filePath string) (rules []ignoreRule, err error) {
+
+	file, err := os.Open(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreRule(scanner.Text()); ok {
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules, scanner.Err()
+}
+
+func parseIgnoreRule(line string) (rule ignoreRule, ok bool) {
+
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false
+	}
+
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+
+	if line == "" {
+		return rule, false
+	}
+
+	// a pattern without a slash matches at any level below the .deploifaiignore file,
+	// otherwise it is relative to the directory of the .deploifaiignore file
+	if strings.Contains(line, "/") {
+		rule.pattern = strings.TrimPrefix(line, "/")
+	} else {
+		rule.pattern = "**/" + line
+	}
+
+	return rule, true
+}
+
+// ancestorDirs returns the directories that contain key, from the dataset directory, which is "", downwards.
+func ancestorDirs(key string) []string {
+
+	dirs := []string{""}
+
+	parts := strings.Split(key, "/")
+	for i := 1; i < len(parts); i++ {
+		dirs = append(dirs, strings.Join(parts[:i], "/"))
+	}
+
+	return dirs
+}
@@ -0,0 +1,79 @@
+package dataset
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestParseIgnoreRule(t *testing.T) {
+	tests := []struct {
+		line string
+		rule ignoreRule
+		ok   bool
+	}{
+		{line: "", ok: false},
+		{line: "   ", ok: false},
+		{line: "# comment", ok: false},
+		{line: "!", ok: false},
+		{line: "/", ok: false},
+		{line: "*.tmp", rule: ignoreRule{pattern: "**/*.tmp"}, ok: true},
+		{line: "*.tmp  \r", rule: ignoreRule{pattern: "**/*.tmp"}, ok: true},
+		{line: "!keep.tmp", rule: ignoreRule{pattern: "**/keep.tmp", negate: true}, ok: true},
+		{line: "cache/", rule: ignoreRule{pattern: "**/cache", dirOnly: true}, ok: true},
+		{line: "/build", rule: ignoreRule{pattern: "build"}, ok: true},
+		{line: "data/raw/*.csv", rule: ignoreRule{pattern: "data/raw/*.csv"}, ok: true},
+		{line: `\#hash`, rule: ignoreRule{pattern: "**/#hash"}, ok: true},
+		{line: `\!bang`, rule: ignoreRule{pattern: "**/!bang"}, ok: true},
+	}
+
+	for _, tt := range tests {
+		rule, ok := parseIgnoreRule(tt.line)
+		// the rule of a line that is not a rule does not matter
+		if ok != tt.ok || (ok && rule != tt.rule) {
+			t.Errorf("parseIgnoreRule(%q) = %+v, %v, want %+v, %v", tt.line, rule, ok, tt.rule, tt.ok)
+		}
+	}
+}
+
+func TestIsIgnored(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, ignoreFilename), "*.tmp\n!keep.tmp\ncache/\n/build\n")
+	writeTestFile(t, filepath.Join(dir, "sub", ignoreFilename), "*.csv\n!keep.tmp\n")
+	writeTestFile(t, filepath.Join(dir, "other", ignoreFilename), "!*.tmp\n")
+
+	tests := []struct {
+		key     string
+		isDir   bool
+		ignored bool
+	}{
+		{key: ".", isDir: true, ignored: false},
+		{key: "a.txt", ignored: false},
+		{key: "a.tmp", ignored: true},
+		{key: "deep/down/a.tmp", ignored: true},
+		{key: "keep.tmp", ignored: false},
+		{key: "cache", isDir: true, ignored: true},
+		{key: "cache", isDir: false, ignored: false},
+		{key: "cache/a.txt", ignored: true},
+		{key: "sub/cache/a.txt", ignored: true},
+		// a file in an ignored directory stays ignored even if a rule would re-include it
+		{key: "cache/keep.tmp", ignored: true},
+		{key: "build", isDir: true, ignored: true},
+		{key: "sub/build", isDir: true, ignored: false},
+		// rules only apply under the directory of their file, and deeper files take precedence
+		{key: "a.csv", ignored: false},
+		{key: "sub/a.csv", ignored: true},
+		{key: "sub/deep/a.csv", ignored: true},
+		{key: "other/a.tmp", ignored: false},
+	}
+
+	matcher := newIgnoreMatcher(dir)
+	for _, tt := range tests {
+		ignored, err := matcher.isIgnored(tt.key, tt.isDir)
+		if err != nil {
+			t.Fatalf("isIgnored(%q, %v) returned an error: %v", tt.key, tt.isDir, err)
+		}
+		if ignored != tt.ignored {
+			t.Errorf("isIgnored(%q, %v) = %v, want %v", tt.key, tt.isDir, ignored, tt.ignored)
+		}
+	}
+}
@@ -53,10 +53,7 @@ If the current working directory or any of its parent directories is already ini
 		var dataStorage generated.DataStorageFragment
 
 		if name != "" {
-			dataStorage, err = findDataStorage(cmd.Context(), *client, whereAccount, generated.DataStorageWhereInput{
-				Projects: &generated.ProjectListRelationFilter{Some: &generated.ProjectWhereInput{ID: &generated.StringFilter{Equals: &projectId}}},
-				Name:     &generated.StringFilter{Equals: &name},
-			})
+			dataStorage, err = findDataStorage(cmd.Context(), *client, whereAccount, projectId, name)
 			if err != nil {
 				return err
 			}
@@ -96,27 +93,35 @@ func init() {
 	initCmd.Flags().StringVarP(&name, "name", "n", "", "name of dataset in the project to use")
 }
 
-func findDataStorage(ctx context.Context, client dataset.Client, whereAccount generated.AccountWhereUniqueInput, whereDataStorage generated.DataStorageWhereInput) (generated.DataStorageFragment, error) {
+// findDataStorage finds the dataset with the given name in a project.
+func findDataStorage(ctx context.Context, client dataset.Client, whereAccount generated.AccountWhereUniqueInput, projectId string, dataStorageName string) (generated.DataStorageFragment, error) {
 
-	status := generated.DataStorageStatusDeploySuccess
-
-	data, err := client.List(ctx, whereAccount, &generated.DataStorageWhereInput{
-		And: []*generated.DataStorageWhereInput{
-			&whereDataStorage,
-			{Status: &generated.EnumDataStorageStatusFilter{Equals: &status}},
-		},
+	data, err := listDataStorage(ctx, client, whereAccount, generated.DataStorageWhereInput{
+		Projects: &generated.ProjectListRelationFilter{Some: &generated.ProjectWhereInput{ID: &generated.StringFilter{Equals: &projectId}}},
+		Name:     &generated.StringFilter{Equals: &dataStorageName},
 	})
 	if err != nil {
 		return generated.DataStorageFragment{}, err
 	}
 
 	if len(data) == 0 {
-		return generated.DataStorageFragment{}, errors.New(fmt.Sprintf("no dataset found with name %s in this project", name))
+		return generated.DataStorageFragment{}, errors.New(fmt.Sprintf("no dataset found with name %s in this project", dataStorageName))
 	}
 
 	return data[0], nil
 }
 
+// findProjectDataStorage finds a dataset by name in the current project.
+func findProjectDataStorage(cmd *cobra.Command, dataStorageName string) (generated.DataStorageFragment, error) {
+
+	_context := ctx.GetContextValue(cmd)
+
+	client := dataset.NewFromConfig(*_context.ServiceClientConfig)
+	whereAccount := generated.AccountWhereUniqueInput{Username: &_context.Root.Workspace.Username}
+
+	return findDataStorage(cmd.Context(), *client, whereAccount, _context.Project.Project.ID, dataStorageName)
+}
+
 func listDataStorage(ctx context.Context, client dataset.Client, whereAccount generated.AccountWhereUniqueInput, whereDataStorage generated.DataStorageWhereInput) ([]generated.DataStorageFragment, error) {
 
 	status := generated.DataStorageStatusDeploySuccess
@@ -155,16 +160,23 @@ func saveInConfig(projectConfig *project_config.Config, dataStorageId string) er
 		return err
 	}
 
-	relativeDirectory, err := filepath.Rel(filepath.Dir(projectConfig.ConfigFile), currentWorkingDirectory)
+	return saveDirInConfig(projectConfig, dataStorageId, currentWorkingDirectory)
+}
+
+// saveDirInConfig maps the dataset to dirAbsPath, relative to the project config file.
+func saveDirInConfig(projectConfig *project_config.Config, dataStorageId string, dirAbsPath string) error {
+
+	relativeDirectory, err := filepath.Rel(filepath.Dir(projectConfig.ConfigFile), dirAbsPath)
 	if err != nil {
 		return err
 	}
 	relativeDirectory = filepath.ToSlash(relativeDirectory)
 
-	projectConfig.Datasets[dataStorageId] = project_config.Dataset{
-		ID:             dataStorageId,
-		LocalDirectory: relativeDirectory,
-	}
+	// keep the rest of the mapping, e.g. the pinned snapshot, when a dataset is relinked
+	d := projectConfig.Datasets[dataStorageId]
+	d.ID = dataStorageId
+	d.LocalDirectory = relativeDirectory
+	projectConfig.Datasets[dataStorageId] = d
 
 	return nil
 }
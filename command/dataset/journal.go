@@ -0,0 +1,295 @@
+package dataset
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/deploifai/cli-go/command/dataset/data_storage"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const journalFilename = "journal.jsonl"
+
+type journalEntryType string
+
+const (
+	// JournalEntryTypeStart starts a transfer, with the operation and the remote object prefixes it transfers
+	JournalEntryTypeStart journalEntryType = "start"
+	// JournalEntryTypeObject is an object that was transferred
+	JournalEntryTypeObject journalEntryType = "object"
+	// JournalEntryTypeUpload starts uploading a large file in parts
+	JournalEntryTypeUpload journalEntryType = "upload"
+	// JournalEntryTypePart is a part of a large file that was uploaded
+	JournalEntryTypePart journalEntryType = "part"
+)
+
+type journalEntry struct {
+	Type journalEntryType `json:"type"`
+
+	Operation string   `json:"operation,omitempty"`
+	Prefixes  []string `json:"prefixes,omitempty"`
+
+	Key     string    `json:"key,omitempty"`
+	Size    int64     `json:"size,omitempty"`
+	ModTime time.Time `json:"modTime,omitempty"`
+	MD5     string    `json:"md5,omitempty"`
+	ETag    string    `json:"eTag,omitempty"`
+
+	UploadID   string `json:"uploadId,omitempty"`
+	PartNumber int    `json:"partNumber,omitempty"`
+}
+
+// journalUpload is a large file that is being uploaded in parts.
+type journalUpload struct {
+	UploadID string
+	Size     int64
+	ModTime  time.Time
+	// ETags of the parts that were uploaded, by part number
+	Parts map[int]string
+}
+
+// journal logs the objects that a push or pull has transferred as each of them completes,
+// and the parts of large files that were uploaded, so that a transfer that was interrupted continues where it stopped.
+// It is removed once the transfer has completed and the manifest is saved.
+type journal struct {
+	// Operation and Prefixes of the transfer that was interrupted, if any
+	Operation string
+	Prefixes  []string
+
+	// objects that were transferred, which are not in the manifest yet
+	objects map[string]manifestEntry
+	uploads map[string]*journalUpload
+
+	path  string
+	file  *os.File
+	mutex sync.Mutex
+}
+
+// loadJournal loads the journal of a transfer in a dataset directory that was interrupted.
+// If there is none, an empty journal is returned.
+// A truncated last line, written when the transfer was interrupted, is ignored.
+func loadJournal(datasetDirPath string) (*journal, error) {
+
+	j := &journal{
+		objects: map[string]manifestEntry{},
+		uploads: map[string]*journalUpload{},
+		path:    filepath.Join(datasetDirPath, localStateDirName, journalFilename),
+	}
+
+	file, err := os.Open(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return j, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var e journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			break
+		}
+		j.apply(e)
+	}
+
+	return j, scanner.Err()
+}
+
+func (j *journal) apply(e journalEntry) {
+	switch e.Type {
+	case JournalEntryTypeStart:
+		j.Operation = e.Operation
+		j.Prefixes = e.Prefixes
+	case JournalEntryTypeObject:
+		j.objects[e.Key] = manifestEntry{Size: e.Size, ModTime: e.ModTime, MD5: e.MD5, ETag: e.ETag}
+		delete(j.uploads, e.Key)
+	case JournalEntryTypeUpload:
+		j.uploads[e.Key] = &journalUpload{UploadID: e.UploadID, Size: e.Size, ModTime: e.ModTime, Parts: map[int]string{}}
+	case JournalEntryTypePart:
+		if u, ok := j.uploads[e.Key]; ok && u.UploadID == e.UploadID {
+			u.Parts[e.PartNumber] = e.ETag
+		}
+	}
+}
+
+// interrupted reports whether a transfer was interrupted.
+func (j *journal) interrupted() bool {
+	return j.Operation != ""
+}
+
+// replay records the objects that the interrupted transfer had transferred in the manifest, and returns how many there were.
+func (j *journal) replay(m *manifest) int {
+
+	for key, entry := range j.objects {
+		m.set(localObject{Key: key, Size: entry.Size, ModTime: entry.ModTime}, entry.MD5, entry.ETag)
+	}
+
+	return len(j.objects)
+}
+
+// start starts logging a transfer, carrying over the objects and the parts of large files that the interrupted transfer had transferred.
+func (j *journal) start(operation string, prefixes []string) error {
+
+	if err := os.MkdirAll(filepath.Dir(j.path), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(j.path)
+	if err != nil {
+		return err
+	}
+	j.file = file
+
+	if err := j.write(journalEntry{Type: JournalEntryTypeStart, Operation: operation, Prefixes: prefixes}); err != nil {
+		return err
+	}
+
+	// objects of the interrupted transfer are only in the manifest once it is saved, so they are logged again until then
+	for key, entry := range j.objects {
+		if err := j.write(journalEntry{Type: JournalEntryTypeObject, Key: key, Size: entry.Size, ModTime: entry.ModTime, MD5: entry.MD5, ETag: entry.ETag}); err != nil {
+			return err
+		}
+	}
+
+	for key, u := range j.uploads {
+		if err := j.write(journalEntry{Type: JournalEntryTypeUpload, Key: key, UploadID: u.UploadID, Size: u.Size, ModTime: u.ModTime}); err != nil {
+			return err
+		}
+		for n, eTag := range u.Parts {
+			if err := j.write(journalEntry{Type: JournalEntryTypePart, Key: key, UploadID: u.UploadID, PartNumber: n, ETag: eTag}); err != nil {
+				return err
+			}
+		}
+	}
+
+	j.Operation = operation
+	j.Prefixes = prefixes
+
+	return nil
+}
+
+func (j *journal) write(e journalEntry) error {
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = j.file.Write(append(data, '\n'))
+
+	return err
+}
+
+// recordObject logs an object that was transferred, along with its local file.
+func (j *journal) recordObject(o localObject, md5 string, eTag string) error {
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	delete(j.uploads, o.Key)
+
+	return j.write(journalEntry{Type: JournalEntryTypeObject, Key: o.Key, Size: o.Size, ModTime: o.ModTime, MD5: md5, ETag: eTag})
+}
+
+// upload returns the upload of a large file that was started, if the file has not changed since.
+func (j *journal) upload(o localObject) (u *journalUpload, ok bool) {
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	u, ok = j.uploads[o.Key]
+	if !ok || u.Size != o.Size || !u.ModTime.Equal(o.ModTime) {
+		return nil, false
+	}
+
+	return u, true
+}
+
+// staleUpload returns the upload of a large file that was started, if the file has changed since.
+func (j *journal) staleUpload(o localObject) (u *journalUpload, ok bool) {
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	u, ok = j.uploads[o.Key]
+	if !ok || (u.Size == o.Size && u.ModTime.Equal(o.ModTime)) {
+		return nil, false
+	}
+
+	return u, true
+}
+
+// startUpload logs the start of uploading a large file in parts.
+func (j *journal) startUpload(o localObject, uploadId string) (*journalUpload, error) {
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	u := &journalUpload{UploadID: uploadId, Size: o.Size, ModTime: o.ModTime, Parts: map[int]string{}}
+	j.uploads[o.Key] = u
+
+	return u, j.write(journalEntry{Type: JournalEntryTypeUpload, Key: o.Key, UploadID: uploadId, Size: o.Size, ModTime: o.ModTime})
+}
+
+// recordPart logs a part of a large file that was uploaded.
+func (j *journal) recordPart(key string, u *journalUpload, partNumber int, eTag string) error {
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	u.Parts[partNumber] = eTag
+
+	return j.write(journalEntry{Type: JournalEntryTypePart, Key: key, UploadID: u.UploadID, PartNumber: partNumber, ETag: eTag})
+}
+
+// finish removes the journal once the transfer has completed and the manifest is saved.
+// Large files that are still being uploaded in parts were left out of the transfer, so their uploads are aborted.
+func (j *journal) finish(client data_storage.Client) error {
+
+	if multipartClient, ok := client.(data_storage.MultipartClient); ok {
+		for key, u := range j.uploads {
+			_ = multipartClient.AbortMultipartUpload(key, u.UploadID)
+		}
+	}
+
+	j.close()
+
+	if err := os.Remove(j.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// close closes the journal, keeping it so that the transfer can be resumed.
+func (j *journal) close() {
+	if j.file != nil {
+		_ = j.file.Close()
+	}
+}
+
+// getResumeArgs returns the paths of the transfer that was interrupted, relative to the current directory,
+// for --resume.
+func getResumeArgs(j *journal, datasetDirPath string, operation string) ([]string, error) {
+
+	if !j.interrupted() {
+		return nil, errors.New(fmt.Sprintf("there is no interrupted %s to resume", operation))
+	}
+	if j.Operation != operation {
+		return nil, errors.New(fmt.Sprintf("there is no interrupted %s to resume, the interrupted transfer is a %s", operation, j.Operation))
+	}
+
+	var args []string
+	for _, prefix := range j.Prefixes {
+		args = append(args, displayPath(filepath.Join(datasetDirPath, filepath.FromSlash(prefix))))
+	}
+
+	return args, nil
+}
@@ -0,0 +1,172 @@
+package dataset
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJournalApply(t *testing.T) {
+	modTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		entries   []journalEntry
+		operation string
+		objects   map[string]manifestEntry
+		uploads   map[string]*journalUpload
+	}{
+		{
+			name:    "empty",
+			objects: map[string]manifestEntry{},
+			uploads: map[string]*journalUpload{},
+		},
+		{
+			name: "start and objects",
+			entries: []journalEntry{
+				{Type: JournalEntryTypeStart, Operation: "push", Prefixes: []string{"data/"}},
+				{Type: JournalEntryTypeObject, Key: "data/a.txt", Size: 5, ModTime: modTime, MD5: "md5", ETag: "etag"},
+			},
+			operation: "push",
+			objects:   map[string]manifestEntry{"data/a.txt": {Size: 5, ModTime: modTime, MD5: "md5", ETag: "etag"}},
+			uploads:   map[string]*journalUpload{},
+		},
+		{
+			name: "parts of an upload",
+			entries: []journalEntry{
+				{Type: JournalEntryTypeStart, Operation: "push"},
+				{Type: JournalEntryTypeUpload, Key: "big.bin", UploadID: "u1", Size: 100, ModTime: modTime},
+				{Type: JournalEntryTypePart, Key: "big.bin", UploadID: "u1", PartNumber: 1, ETag: "p1"},
+				{Type: JournalEntryTypePart, Key: "big.bin", UploadID: "u1", PartNumber: 2, ETag: "p2"},
+				// parts of another upload of the same file are not mixed in
+				{Type: JournalEntryTypePart, Key: "big.bin", UploadID: "u0", PartNumber: 3, ETag: "p3"},
+				// parts of a file that has no upload are ignored
+				{Type: JournalEntryTypePart, Key: "other.bin", UploadID: "u2", PartNumber: 1, ETag: "p1"},
+			},
+			operation: "push",
+			objects:   map[string]manifestEntry{},
+			uploads: map[string]*journalUpload{
+				"big.bin": {UploadID: "u1", Size: 100, ModTime: modTime, Parts: map[int]string{1: "p1", 2: "p2"}},
+			},
+		},
+		{
+			name: "completed upload",
+			entries: []journalEntry{
+				{Type: JournalEntryTypeStart, Operation: "push"},
+				{Type: JournalEntryTypeUpload, Key: "big.bin", UploadID: "u1", Size: 100, ModTime: modTime},
+				{Type: JournalEntryTypePart, Key: "big.bin", UploadID: "u1", PartNumber: 1, ETag: "p1"},
+				{Type: JournalEntryTypeObject, Key: "big.bin", Size: 100, ModTime: modTime, ETag: "etag-1"},
+			},
+			operation: "push",
+			objects:   map[string]manifestEntry{"big.bin": {Size: 100, ModTime: modTime, ETag: "etag-1"}},
+			uploads:   map[string]*journalUpload{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := &journal{objects: map[string]manifestEntry{}, uploads: map[string]*journalUpload{}}
+			for _, e := range tt.entries {
+				j.apply(e)
+			}
+
+			if j.Operation != tt.operation {
+				t.Errorf("Operation = %q, want %q", j.Operation, tt.operation)
+			}
+			if j.interrupted() != (tt.operation != "") {
+				t.Errorf("interrupted() = %v, want %v", j.interrupted(), tt.operation != "")
+			}
+			if !reflect.DeepEqual(j.objects, tt.objects) {
+				t.Errorf("objects = %+v, want %+v", j.objects, tt.objects)
+			}
+			if !reflect.DeepEqual(j.uploads, tt.uploads) {
+				t.Errorf("uploads = %+v, want %+v", j.uploads, tt.uploads)
+			}
+		})
+	}
+}
+
+func TestJournalReplay(t *testing.T) {
+	modTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	j := &journal{
+		objects: map[string]manifestEntry{
+			"a.txt": {Size: 5, ModTime: modTime, MD5: "md5-a", ETag: "etag-a"},
+			"b.txt": {Size: 6, ModTime: modTime, ETag: "etag-b"},
+		},
+		uploads: map[string]*journalUpload{},
+	}
+	m := &manifest{Files: map[string]manifestEntry{
+		"a.txt": {Size: 1, ModTime: modTime, MD5: "old"},
+		"c.txt": {Size: 7, ModTime: modTime, MD5: "md5-c"},
+	}}
+
+	if n := j.replay(m); n != 2 {
+		t.Errorf("replay() = %d, want 2", n)
+	}
+
+	want := map[string]manifestEntry{
+		"a.txt": {Size: 5, ModTime: modTime, MD5: "md5-a", ETag: "etag-a"},
+		"b.txt": {Size: 6, ModTime: modTime, ETag: "etag-b"},
+		"c.txt": {Size: 7, ModTime: modTime, MD5: "md5-c"},
+	}
+	if !reflect.DeepEqual(m.Files, want) {
+		t.Errorf("manifest = %+v, want %+v", m.Files, want)
+	}
+}
+
+func TestLoadInterruptedJournal(t *testing.T) {
+	dir := t.TempDir()
+	modTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	j, err := loadJournal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j.interrupted() {
+		t.Fatal("a missing journal is interrupted")
+	}
+
+	if err := j.start("push", []string{"data/"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.recordObject(localObject{Key: "data/a.txt", Size: 5, ModTime: modTime}, "md5", "etag"); err != nil {
+		t.Fatal(err)
+	}
+	u, err := j.startUpload(localObject{Key: "data/big.bin", Size: 100, ModTime: modTime}, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.recordPart("data/big.bin", u, 1, "p1"); err != nil {
+		t.Fatal(err)
+	}
+	// the transfer is interrupted while a line is written
+	if _, err := j.file.WriteString(`{"type":"part","key":"data/big.bin","uploadId":"u1","partNum`); err != nil {
+		t.Fatal(err)
+	}
+	j.close()
+
+	resumed, err := loadJournal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !resumed.interrupted() || resumed.Operation != "push" || !reflect.DeepEqual(resumed.Prefixes, []string{"data/"}) {
+		t.Errorf("loaded the transfer %q %v, want push [data/]", resumed.Operation, resumed.Prefixes)
+	}
+	wantObjects := map[string]manifestEntry{"data/a.txt": {Size: 5, ModTime: modTime, MD5: "md5", ETag: "etag"}}
+	if len(resumed.objects) != 1 || !resumed.objects["data/a.txt"].ModTime.Equal(modTime) || resumed.objects["data/a.txt"].ETag != "etag" {
+		t.Errorf("objects = %+v, want %+v", resumed.objects, wantObjects)
+	}
+	if u, ok := resumed.upload(localObject{Key: "data/big.bin", Size: 100, ModTime: modTime}); !ok || !reflect.DeepEqual(u.Parts, map[int]string{1: "p1"}) {
+		t.Errorf("upload() = %+v, %v, want the upload with part 1", u, ok)
+	}
+
+	if err := resumed.finish(newFakeClient()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(resumed.path); !os.IsNotExist(err) {
+		t.Errorf("the journal was not removed: %v", err)
+	}
+}
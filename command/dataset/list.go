@@ -0,0 +1,207 @@
+/*
+Copyright © 2023 Sean Chok
+*/
+package dataset
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/deploifai/cli-go/command/command_config/project_config"
+	"github.com/deploifai/cli-go/command/ctx"
+	"github.com/deploifai/cli-go/command/dataset/data_storage"
+	"github.com/deploifai/sdk-go/api"
+	"github.com/deploifai/sdk-go/api/generated"
+	"github.com/spf13/cobra"
+	"sort"
+	"text/tabwriter"
+)
+
+var listJSON bool
+var listNoSize bool
+
+type datasetInList struct {
+	ID             string                      `json:"id"`
+	Name           string                      `json:"name"`
+	Provider       generated.CloudProvider     `json:"provider,omitempty"`
+	Status         generated.DataStorageStatus `json:"status,omitempty"`
+	Size           *int64                      `json:"size,omitempty"`
+	Files          *int                        `json:"files,omitempty"`
+	SizeError      string                      `json:"sizeError,omitempty"`
+	LocalDirectory string                      `json:"localDirectory,omitempty"`
+}
+
+// listCmd represents the list command
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List datasets in the current project",
+	Long: `List the datasets in the current project, with their cloud provider, deploy status and size,
+and the local directory each one is initialised in, if any.
+
+Datasets that are initialised in the project config but are not found in the project are listed too, without a status.
+Use --no-size to skip listing the files of every dataset to work out its size.
+The size of a dataset whose files cannot be listed is shown as "?", and the error is printed.
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		_context := ctx.GetContextValue(cmd)
+
+		if !_context.Project.Project.IsInitialized() {
+			return project_config.ProjectNotInitializedError{}
+		}
+
+		projectId := _context.Project.Project.ID
+		whereAccount := generated.AccountWhereUniqueInput{Username: &_context.Root.Workspace.Username}
+
+		dataStorages, err := getDataStoragesWithStatus(cmd.Context(), _context.ServiceClientConfig.API, whereAccount, projectId)
+		if err != nil {
+			return err
+		}
+
+		datasets := make([]datasetInList, 0, len(dataStorages))
+		found := map[string]bool{}
+
+		for _, d := range dataStorages {
+			entry := datasetInList{
+				ID:             d.ID,
+				Name:           d.Name,
+				Status:         d.Status,
+				LocalDirectory: _context.Project.Datasets[d.ID].LocalDirectory,
+			}
+			if d.CloudProfile != nil {
+				entry.Provider = d.CloudProfile.Provider
+			}
+
+			// only a deployed dataset has storage to list, and a dataset whose storage cannot be listed does not stop the others
+			if !listNoSize && d.Status == generated.DataStorageStatusDeploySuccess {
+				if err := sumDatasetSize(cmd, d.ID, &entry); err != nil {
+					entry.SizeError = err.Error()
+					cmd.PrintErrf("Warning: cannot list the files of dataset %s, %s\n", d.Name, err)
+				}
+			}
+
+			datasets = append(datasets, entry)
+			found[d.ID] = true
+		}
+
+		// datasets that are initialised locally but no longer in the project
+		for id, d := range _context.Project.Datasets {
+			if !found[id] {
+				datasets = append(datasets, datasetInList{ID: id, LocalDirectory: d.LocalDirectory})
+			}
+		}
+
+		sort.Slice(datasets, func(i, j int) bool {
+			if datasets[i].Name != datasets[j].Name {
+				return datasets[i].Name < datasets[j].Name
+			}
+			return datasets[i].ID < datasets[j].ID
+		})
+
+		if listJSON {
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+
+			return encoder.Encode(datasets)
+		}
+
+		printDatasets(cmd, datasets)
+
+		return nil
+	},
+}
+
+func init() {
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// listCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// listCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	listCmd.Flags().BoolVar(&listJSON, "json", false, "print the datasets as JSON")
+	listCmd.Flags().BoolVar(&listNoSize, "no-size", false, "do not list the files of the datasets to show their size")
+}
+
+type dataStorageWithStatus struct {
+	dataStorageStatus
+	CloudProfile *generated.CloudProfileFragment `json:"cloudProfile"`
+}
+
+// getDataStoragesWithStatus returns the data storages of a project with their deploy status.
+// The data storage fragment of the SDK has no deploy status, and the SDK has no operation that lists it,
+// so they are listed with GetDataStoragesWithStatus from query.gql instead.
+func getDataStoragesWithStatus(c context.Context, provider api.Provider, whereAccount generated.AccountWhereUniqueInput, projectId string) ([]dataStorageWithStatus, error) {
+
+	var res struct {
+		DataStorages []dataStorageWithStatus `json:"dataStorages"`
+	}
+
+	err := postGQL(c, provider, "GetDataStoragesWithStatus", &res, map[string]interface{}{
+		"whereAccount": whereAccount,
+		"whereDataStorage": generated.DataStorageWhereInput{
+			Projects: &generated.ProjectListRelationFilter{Some: &generated.ProjectWhereInput{ID: &generated.StringFilter{Equals: &projectId}}},
+		},
+	})
+
+	return res.DataStorages, err
+}
+
+// sumDatasetSize lists the files of a dataset to set the size and number of files of its entry.
+func sumDatasetSize(cmd *cobra.Command, id string, entry *datasetInList) error {
+
+	client, err := data_storage.New(cmd.Context(), ctx.GetContextValue(cmd).ServiceClientConfig.API, id, nil)
+	if err != nil {
+		return err
+	}
+	remoteObjects, err := listRemoteObjects(client, "")
+	if err != nil {
+		return err
+	}
+
+	var size int64
+	for _, o := range remoteObjects {
+		size += o.Size
+	}
+	files := len(remoteObjects)
+	entry.Size, entry.Files = &size, &files
+
+	return nil
+}
+
+func printDatasets(cmd *cobra.Command, datasets []datasetInList) {
+
+	if len(datasets) == 0 {
+		cmd.Println("No datasets found in this project")
+		return
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprintln(w, "NAME\tPROVIDER\tSTATUS\tSIZE\tFILES\tLOCAL DIRECTORY")
+
+	for _, d := range datasets {
+		name, provider, status, size, files, dir := d.Name, string(d.Provider), string(d.Status), "-", "-", d.LocalDirectory
+		if name == "" {
+			name, status = d.ID, "not found in project"
+		}
+		if provider == "" {
+			provider = "-"
+		}
+		if d.Size != nil {
+			size, files = formatBytes(*d.Size), fmt.Sprintf("%d", *d.Files)
+		} else if d.SizeError != "" {
+			size, files = "?", "?"
+		}
+		if dir == "" {
+			dir = "-"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", name, provider, status, size, files, dir)
+	}
+
+	_ = w.Flush()
+}
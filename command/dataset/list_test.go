@@ -0,0 +1,59 @@
+package dataset
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/deploifai/sdk-go/api"
+	"github.com/deploifai/sdk-go/api/generated"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetDataStoragesWithStatus(t *testing.T) {
+	var request struct {
+		OperationName string `json:"operationName"`
+		Variables     struct {
+			WhereAccount     generated.AccountWhereUniqueInput `json:"whereAccount"`
+			WhereDataStorage generated.DataStorageWhereInput   `json:"whereDataStorage"`
+		} `json:"variables"`
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Error(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"dataStorages": [
+			{"id": "1", "name": "images", "status": "DEPLOY_SUCCESS", "cloudProfile": {"id": "cp", "name": "aws", "provider": "AWS"}},
+			{"id": "2", "name": "labels", "status": "DEPLOYING", "cloudProfile": null}
+		]}}`))
+	}))
+	defer server.Close()
+
+	username := "alice"
+	dataStorages, err := getDataStoragesWithStatus(context.Background(), api.NewAPI(server.URL, server.URL, nil), generated.AccountWhereUniqueInput{Username: &username}, "project")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if request.OperationName != "GetDataStoragesWithStatus" {
+		t.Errorf("sent operation %q, want GetDataStoragesWithStatus", request.OperationName)
+	}
+	if u := request.Variables.WhereAccount.Username; u == nil || *u != username {
+		t.Errorf("sent whereAccount %+v, want the username %s", request.Variables.WhereAccount, username)
+	}
+	if p := request.Variables.WhereDataStorage.Projects; p == nil || p.Some == nil || p.Some.ID == nil || p.Some.ID.Equals == nil || *p.Some.ID.Equals != "project" {
+		t.Errorf("sent whereDataStorage %+v, want the data storages of the project", request.Variables.WhereDataStorage)
+	}
+
+	if len(dataStorages) != 2 {
+		t.Fatalf("getDataStoragesWithStatus() returned %d data storages, want 2", len(dataStorages))
+	}
+	if d := dataStorages[0]; d.ID != "1" || d.Name != "images" || d.Status != generated.DataStorageStatusDeploySuccess || d.CloudProfile == nil || d.CloudProfile.Provider != generated.CloudProviderAws {
+		t.Errorf("getDataStoragesWithStatus()[0] = %+v, want images, deployed on AWS", d)
+	}
+	if d := dataStorages[1]; d.ID != "2" || d.Status != generated.DataStorageStatusDeploying || d.CloudProfile != nil {
+		t.Errorf("getDataStoragesWithStatus()[1] = %+v, want labels, deploying without a cloud profile", d)
+	}
+}
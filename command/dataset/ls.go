@@ -0,0 +1,314 @@
+/*
+Copyright © 2023 Sean Chok
+*/
+package dataset
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/deploifai/cli-go/command/ctx"
+	"github.com/deploifai/cli-go/command/dataset/data_storage"
+	"github.com/deploifai/sdk-go/service/dataset"
+	"github.com/spf13/cobra"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+var lsLong bool
+var lsRecursive bool
+var lsTree bool
+var lsJSON bool
+
+type lsEntryType string
+
+const (
+	LsEntryTypeFile      lsEntryType = "file"
+	LsEntryTypeDirectory lsEntryType = "directory"
+)
+
+// lsEntry is a file or directory in a dataset, as printed with --json.
+// The size and last modified time of a directory are those of all the files under it.
+type lsEntry struct {
+	Key          string      `json:"key"`
+	Type         lsEntryType `json:"type"`
+	Size         int64       `json:"size"`
+	LastModified time.Time   `json:"lastModified"`
+	Files        int         `json:"files,omitempty"`
+	Children     []lsEntry   `json:"children,omitempty"`
+}
+
+// lsNode is a file or directory in the tree of objects that ls builds from the keys of a listing.
+type lsNode struct {
+	Name     string
+	Entry    lsEntry
+	Children map[string]*lsNode
+}
+
+// lsCmd represents the ls command
+var lsCmd = &cobra.Command{
+	Use:   "ls [<path>]",
+	Short: "List the files in a dataset",
+	Long: `List the files and directories in a dataset, as they are stored on the cloud, without pulling them.
+
+<path> is resolved like the paths of "deploifai dataset pull", relative to the current directory,
+and does not have to exist locally. If no <path> is specified, the current directory is used.
+
+Use -l to show the size and last modified time of each file, and of all the files in each directory.
+Use -R to list every file under <path>, or --tree to show them as a tree.
+Use --json to print the files as JSON, with sizes in bytes, nested in their directories with --tree.
+`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		_context := ctx.GetContextValue(cmd)
+
+		// get the dataset and directory path from config
+		ok, ds, datasetDirPath, err := getTargetDataset(cmd)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("the current directory is not initialised as a dataset, use --dataset to choose one")
+		}
+
+		absPaths, err := getAbsPaths(datasetDirPath, args)
+		if err != nil {
+			return err
+		}
+
+		// verify the path, which does not have to exist locally
+		if ok, invalidArgs, err := verifyPullPaths(datasetDirPath, args, absPaths); err != nil {
+			return err
+		} else if !ok {
+			return errors.New(fmt.Sprintf("invalid path: %s", strings.Join(invalidArgs, ", ")))
+		}
+
+		remoteObjectPrefixes, err := getRemoteObjectPrefixes(datasetDirPath, absPaths)
+		if err != nil {
+			return err
+		}
+		remoteObjectPrefix := remoteObjectPrefixes[0]
+
+		client, err := data_storage.New(cmd.Context(), _context.ServiceClientConfig.API, ds.ID, nil)
+		if err != nil {
+			return err
+		}
+
+		remoteObjects, err := listRemoteObjects(client, remoteObjectPrefix)
+		if err != nil {
+			return err
+		}
+
+		if len(remoteObjects) == 0 && len(args) > 0 {
+			return errors.New(fmt.Sprintf("no files found at %s", args[0]))
+		}
+
+		root := newLsTree(remoteObjectPrefix, remoteObjects)
+
+		switch {
+		case lsJSON:
+			var entries []lsEntry
+			switch {
+			case lsTree:
+				entries = root.treeEntries()
+			case lsRecursive:
+				entries = root.fileEntries()
+			default:
+				entries = root.childEntries()
+			}
+			if entries == nil {
+				entries = []lsEntry{}
+			}
+
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+
+			return encoder.Encode(entries)
+		case lsTree:
+			if len(args) > 0 {
+				cmd.Println(args[0])
+			} else {
+				cmd.Println(".")
+			}
+			printLsTree(cmd.OutOrStdout(), root, "")
+			return nil
+		}
+
+		var names []string
+		var entries []lsEntry
+		if lsRecursive {
+			// files are named by their path under <path>
+			entries = root.fileEntries()
+			for _, e := range entries {
+				names = append(names, strings.TrimPrefix(e.Key, dataset.CleanRemoteObjectPrefix(remoteObjectPrefix)))
+			}
+		} else {
+			for _, n := range root.sortedChildren() {
+				entries = append(entries, n.Entry)
+				names = append(names, n.displayName())
+			}
+		}
+
+		if !lsLong {
+			for _, name := range names {
+				cmd.Println(name)
+			}
+			return nil
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', tabwriter.AlignRight)
+		for i, e := range entries {
+			_, _ = fmt.Fprintf(w, "%s\t  %s\t  %s\n", formatBytes(e.Size), e.LastModified.Local().Format(time.DateTime), names[i])
+		}
+
+		return w.Flush()
+	},
+}
+
+func init() {
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// lsCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// lsCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	lsCmd.Flags().BoolVarP(&lsLong, "long", "l", false, "show the size and last modified time of the files")
+	lsCmd.Flags().BoolVarP(&lsRecursive, "recursive", "R", false, "list every file under the path")
+	lsCmd.Flags().BoolVar(&lsTree, "tree", false, "show every file under the path as a tree")
+	lsCmd.Flags().BoolVar(&lsJSON, "json", false, "print the files as JSON")
+}
+
+// newLsTree builds the tree of the objects listed under remoteObjectPrefix, whose root is the directory of the prefix.
+// If the prefix is the key of a single object, the object is the only child of the root.
+func newLsTree(remoteObjectPrefix string, remoteObjects map[string]data_storage.Object) *lsNode {
+
+	prefix := dataset.CleanRemoteObjectPrefix(remoteObjectPrefix)
+
+	root := &lsNode{Entry: lsEntry{Key: prefix, Type: LsEntryTypeDirectory}, Children: map[string]*lsNode{}}
+
+	for key, r := range remoteObjects {
+		rel := strings.TrimPrefix(key, prefix)
+		if key == strings.TrimSuffix(prefix, "/") {
+			rel = path.Base(key)
+		}
+
+		// add up the size of the object in every directory on its way down
+		node := root
+		node.add(r)
+		parts := strings.Split(rel, "/")
+		for i, part := range parts[:len(parts)-1] {
+			child, ok := node.Children[part]
+			if !ok {
+				dirKey := prefix + strings.Join(parts[:i+1], "/") + "/"
+				child = &lsNode{Name: part, Entry: lsEntry{Key: dirKey, Type: LsEntryTypeDirectory}, Children: map[string]*lsNode{}}
+				node.Children[part] = child
+			}
+			node = child
+			node.add(r)
+		}
+
+		name := parts[len(parts)-1]
+		node.Children[name] = &lsNode{Name: name, Entry: lsEntry{Key: key, Type: LsEntryTypeFile, Size: r.Size, LastModified: r.LastModified}}
+	}
+
+	return root
+}
+
+// add counts an object under the directory of n.
+func (n *lsNode) add(r data_storage.Object) {
+	n.Entry.Size += r.Size
+	n.Entry.Files++
+	if r.LastModified.After(n.Entry.LastModified) {
+		n.Entry.LastModified = r.LastModified
+	}
+}
+
+// displayName is the name of n, with a trailing slash for a directory.
+func (n *lsNode) displayName() string {
+	if n.Entry.Type == LsEntryTypeDirectory {
+		return n.Name + "/"
+	}
+	return n.Name
+}
+
+func (n *lsNode) sortedChildren() []*lsNode {
+
+	children := make([]*lsNode, 0, len(n.Children))
+	for _, child := range n.Children {
+		children = append(children, child)
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].Name < children[j].Name
+	})
+
+	return children
+}
+
+// childEntries returns the entries of the files and directories directly under n.
+func (n *lsNode) childEntries() []lsEntry {
+
+	var entries []lsEntry
+	for _, child := range n.sortedChildren() {
+		entries = append(entries, child.Entry)
+	}
+
+	return entries
+}
+
+// fileEntries returns the entries of all the files under n, sorted by key.
+func (n *lsNode) fileEntries() []lsEntry {
+
+	var entries []lsEntry
+	for _, child := range n.sortedChildren() {
+		if child.Entry.Type == LsEntryTypeFile {
+			entries = append(entries, child.Entry)
+		} else {
+			entries = append(entries, child.fileEntries()...)
+		}
+	}
+
+	return entries
+}
+
+// treeEntries returns the entries of the files and directories under n, with the entries of each directory nested in it.
+func (n *lsNode) treeEntries() []lsEntry {
+
+	var entries []lsEntry
+	for _, child := range n.sortedChildren() {
+		entry := child.Entry
+		entry.Children = child.treeEntries()
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// printLsTree prints the files and directories under n as a tree, with their sizes if lsLong is true.
+func printLsTree(w io.Writer, n *lsNode, indent string) {
+
+	children := n.sortedChildren()
+
+	for i, child := range children {
+		branch, childIndent := "├── ", "│   "
+		if i == len(children)-1 {
+			branch, childIndent = "└── ", "    "
+		}
+
+		if lsLong {
+			_, _ = fmt.Fprintf(w, "%s%s%s (%s, %s)\n", indent, branch, child.displayName(), formatBytes(child.Entry.Size), child.Entry.LastModified.Local().Format(time.DateTime))
+		} else {
+			_, _ = fmt.Fprintf(w, "%s%s%s\n", indent, branch, child.displayName())
+		}
+
+		printLsTree(w, child, indent+childIndent)
+	}
+}
@@ -0,0 +1,138 @@
+package dataset
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// localStateDirName is the directory in a dataset directory where the CLI keeps its local state.
+	// It is never transferred.
+	localStateDirName = ".deploifai"
+	manifestFilename  = "manifest.json"
+)
+
+type manifestEntry struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	MD5     string    `json:"md5,omitempty"`
+	ETag    string    `json:"eTag,omitempty"`
+}
+
+// manifest records the files of a dataset directory as they were after they were last transferred successfully.
+// A file that still has the same size and modification time has not changed since, and does not need to be transferred again.
+type manifest struct {
+	Files map[string]manifestEntry `json:"files"`
+
+	path  string
+	mutex sync.Mutex
+}
+
+// loadManifest loads the manifest of a dataset directory.
+// If the manifest is missing or cannot be read, an empty manifest is returned with ok set to false,
+// and err describes why it could not be read.
+func loadManifest(datasetDirPath string) (m *manifest, ok bool, err error) {
+
+	m = &manifest{
+		Files: map[string]manifestEntry{},
+		path:  filepath.Join(datasetDirPath, localStateDirName, manifestFilename),
+	}
+
+	data, err := os.ReadFile(m.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, false, nil
+	} else if err != nil {
+		return m, false, err
+	}
+
+	if err := json.Unmarshal(data, m); err != nil || m.Files == nil {
+		m.Files = map[string]manifestEntry{}
+		return m, false, errors.New("the dataset manifest is corrupt")
+	}
+
+	return m, true, nil
+}
+
+func (m *manifest) save() error {
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return writeStateFile(m.path, m)
+}
+
+// writeStateFile writes v as JSON to a file in the local state directory.
+func writeStateFile(path string, v interface{}) error {
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	// write to a temporary file first so that an interrupted save does not corrupt the file
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+// lookup returns the entry of a local file if the file has not changed since it was recorded.
+func (m *manifest) lookup(o localObject) (entry manifestEntry, ok bool) {
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	entry, ok = m.Files[o.Key]
+	if !ok || entry.Size != o.Size || !entry.ModTime.Equal(o.ModTime) {
+		return manifestEntry{}, false
+	}
+
+	return entry, true
+}
+
+// recorded returns the entry recorded for a key, regardless of whether the local file has changed since.
+func (m *manifest) recorded(key string) (entry manifestEntry, ok bool) {
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	entry, ok = m.Files[key]
+
+	return entry, ok
+}
+
+// set records a local file after it was transferred, with its checksum, if known, and the ETag of its remote object.
+func (m *manifest) set(o localObject, md5 string, eTag string) {
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.Files[o.Key] = manifestEntry{Size: o.Size, ModTime: o.ModTime, MD5: md5, ETag: eTag}
+}
+
+// remove forgets a file after it was deleted, locally or remotely.
+func (m *manifest) remove(key string) {
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.Files, key)
+}
+
+// localObjectMD5 returns the checksum of a local file, from the manifest if the file has not changed since it was recorded.
+func localObjectMD5(m *manifest, o localObject) (string, error) {
+	if entry, ok := m.lookup(o); ok && entry.MD5 != "" {
+		return entry.MD5, nil
+	}
+	return fileMD5(o.AbsPath)
+}
@@ -0,0 +1,106 @@
+package dataset
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestManifestLookup(t *testing.T) {
+	modTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	entry := manifestEntry{Size: 5, ModTime: modTime, MD5: testMD5("hello"), ETag: `"etag"`}
+
+	m := &manifest{Files: map[string]manifestEntry{"a.txt": entry}}
+
+	tests := []struct {
+		name   string
+		object localObject
+		ok     bool
+	}{
+		{name: "unchanged", object: localObject{Key: "a.txt", Size: 5, ModTime: modTime}, ok: true},
+		{name: "same time in another zone", object: localObject{Key: "a.txt", Size: 5, ModTime: modTime.In(time.FixedZone("UTC+8", 8*60*60))}, ok: true},
+		{name: "different size", object: localObject{Key: "a.txt", Size: 6, ModTime: modTime}, ok: false},
+		{name: "different modification time", object: localObject{Key: "a.txt", Size: 5, ModTime: modTime.Add(time.Second)}, ok: false},
+		{name: "not recorded", object: localObject{Key: "b.txt", Size: 5, ModTime: modTime}, ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := m.lookup(tt.object)
+			if ok != tt.ok {
+				t.Fatalf("lookup() ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && got != entry {
+				t.Errorf("lookup() = %+v, want %+v", got, entry)
+			}
+			if !ok && got != (manifestEntry{}) {
+				t.Errorf("lookup() = %+v, want an empty entry", got)
+			}
+		})
+	}
+}
+
+func TestManifestSaveAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	modTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	m, ok, err := loadManifest(dir)
+	if err != nil || ok {
+		t.Fatalf("loadManifest() of a missing manifest = %v, %v, want false, nil", ok, err)
+	}
+
+	m.set(localObject{Key: "a.txt", Size: 5, ModTime: modTime}, testMD5("hello"), `"etag-1"`)
+	// the checksum of a downloaded file is not always known
+	m.set(localObject{Key: "dir/b.txt", Size: 1, ModTime: modTime}, "", `"etag-2"`)
+	// a file that is transferred again replaces its entry
+	m.set(localObject{Key: "a.txt", Size: 6, ModTime: modTime.Add(time.Hour)}, testMD5("hello!"), `"etag-3"`)
+
+	if err := m.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, ok, err := loadManifest(dir)
+	if err != nil || !ok {
+		t.Fatalf("loadManifest() = %v, %v, want true, nil", ok, err)
+	}
+
+	want := map[string]manifestEntry{
+		"a.txt":     {Size: 6, ModTime: modTime.Add(time.Hour), MD5: testMD5("hello!"), ETag: `"etag-3"`},
+		"dir/b.txt": {Size: 1, ModTime: modTime, ETag: `"etag-2"`},
+	}
+	if len(loaded.Files) != len(want) {
+		t.Fatalf("loaded %d entries, want %d", len(loaded.Files), len(want))
+	}
+	for key, w := range want {
+		got, ok := loaded.Files[key]
+		if !ok || got.Size != w.Size || !got.ModTime.Equal(w.ModTime) || got.MD5 != w.MD5 || got.ETag != w.ETag {
+			t.Errorf("entry of %s = %+v, want %+v", key, got, w)
+		}
+	}
+}
+
+func TestLoadCorruptManifest(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, localStateDirName, manifestFilename), `{"files": `)
+
+	m, ok, err := loadManifest(dir)
+	if err == nil || ok {
+		t.Fatalf("loadManifest() of a corrupt manifest = %v, %v, want false and an error", ok, err)
+	}
+	if len(m.Files) != 0 {
+		t.Errorf("loadManifest() of a corrupt manifest has %d entries, want none", len(m.Files))
+	}
+
+	// the manifest is replaced when it is saved again
+	if err := m.save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, localStateDirName, manifestFilename+".tmp")); !os.IsNotExist(err) {
+		t.Errorf("the temporary manifest was left behind: %v", err)
+	}
+	if _, ok, err := loadManifest(dir); err != nil || !ok {
+		t.Errorf("loadManifest() after saving = %v, %v, want true, nil", ok, err)
+	}
+}
@@ -0,0 +1,118 @@
+package dataset
+
+import (
+	"fmt"
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/deploifai/cli-go/command/dataset/data_storage"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// getRemoteObjectsToDelete returns the remote objects that have no local file, for push --delete.
+// Objects ignored by .deploifaiignore files are kept, since they are never pushed in the first place.
+func getRemoteObjectsToDelete(matcher *ignoreMatcher, localObjects map[string]localObject, remoteObjects map[string]data_storage.Object) ([]data_storage.Object, error) {
+
+	var objects []data_storage.Object
+
+	for key, r := range remoteObjects {
+		if _, ok := localObjects[key]; ok {
+			continue
+		}
+		if ignored, err := matcher.isIgnored(key, false); err != nil {
+			return nil, err
+		} else if ignored {
+			continue
+		}
+		objects = append(objects, r)
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+
+	return objects, nil
+}
+
+// getLocalObjectsToDelete returns the local files that have no remote object, for pull --delete.
+func getLocalObjectsToDelete(localObjects map[string]localObject, remoteObjects map[string]data_storage.Object) []localObject {
+
+	var objects []localObject
+
+	for key, o := range localObjects {
+		if _, ok := remoteObjects[key]; !ok {
+			objects = append(objects, o)
+		}
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+
+	return objects
+}
+
+// confirmDelete lists the paths that are about to be deleted, and asks the user to confirm unless yes is set.
+func confirmDelete(paths []string, yes bool) (bool, error) {
+
+	fmt.Printf("The following %d files will be deleted:\n", len(paths))
+	for _, p := range paths {
+		fmt.Printf("  %s\n", p)
+	}
+
+	if yes {
+		return true, nil
+	}
+
+	confirmed := false
+	err := survey.AskOne(&survey.Confirm{
+		Message: fmt.Sprintf("Delete %d files?", len(paths)),
+		Default: false,
+	}, &confirmed)
+
+	return confirmed, err
+}
+
+// deleteRemoteObjects deletes remote objects, and forgets them in the manifest.
+func deleteRemoteObjects(client data_storage.Client, opts transferOptions, m *manifest, objects []data_storage.Object, progressBarDescription string) error {
+
+	// deletions transfer no content, so their progress is counted in files
+	files := make([]transferFile, len(objects))
+	for i, o := range objects {
+		files[i] = transferFile{Name: o.Key}
+	}
+
+	return runDir(opts, progressBarDescription, files, func(i int) error {
+		if err := client.DeleteObject(objects[i].Key); err != nil {
+			return err
+		}
+		m.remove(objects[i].Key)
+		return nil
+	})
+}
+
+// deleteLocalObjects deletes local files, and any directories under rootAbsPath that are left empty,
+// and forgets the files in the manifest.
+func deleteLocalObjects(m *manifest, objects []localObject, rootAbsPath string) error {
+
+	for _, o := range objects {
+		if err := os.Remove(o.AbsPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		m.remove(o.Key)
+
+		// os.Remove fails on directories that are not empty, which is where this stops
+		for dir := filepath.Dir(o.AbsPath); dir != rootAbsPath; dir = filepath.Dir(dir) {
+			if ok, err := isSubDir(rootAbsPath, dir); err != nil || !ok {
+				break
+			}
+			if err := os.Remove(dir); err != nil {
+				break
+			}
+		}
+	}
+
+	fmt.Printf("Deleted %d files\n", len(objects))
+
+	return nil
+}
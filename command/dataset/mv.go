@@ -0,0 +1,52 @@
+/*
+Copyright © 2023 Sean Chok
+*/
+package dataset
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var mvRecursive bool
+var mvTo string
+
+// mvCmd represents the mv command
+var mvCmd = &cobra.Command{
+	Use:   "mv <src> <dest>",
+	Short: "Move files or directories within a dataset, or to another dataset",
+	Long: `Move files or directories in a dataset to other paths, without downloading them to the local filesystem.
+Local files are not changed.
+
+This requires the local directory to be initialised as a dataset first.
+Use the command "deploifai dataset init" to do that.
+
+<src> and <dest> are resolved the same way as for "deploifai dataset pull".
+Use -r to move a directory, in which case the objects under <src> are moved to the same paths under <dest>,
+e.g. "deploifai dataset mv -r imgs images" renames the directory imgs to images.
+A file is moved into <dest> if <dest> ends with a "/", or to <dest> itself otherwise.
+
+Use --to to move to another dataset in the same project, in which case <dest> is relative to the root of that dataset.
+
+Objects are copied by the cloud provider where possible, otherwise they are streamed through this machine.
+Each object is deleted from <src> once it has been copied to <dest>.
+`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return copyObjects(cmd, args[0], args[1], mvRecursive, mvTo, true)
+	},
+}
+
+func init() {
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// mvCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// mvCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	mvCmd.Flags().BoolVarP(&mvRecursive, "recursive", "r", false, "move directories and all the objects in them")
+	mvCmd.Flags().StringVar(&mvTo, "to", "", "name of another dataset in the project to move to")
+}
@@ -0,0 +1,83 @@
+/*
+Copyright © 2023 Sean Chok
+*/
+package dataset
+
+import (
+	"errors"
+	"github.com/deploifai/cli-go/command/ctx"
+	"github.com/deploifai/cli-go/command/dataset/data_storage"
+	"github.com/spf13/cobra"
+)
+
+var pinClear bool
+
+// pinCmd represents the pin command
+var pinCmd = &cobra.Command{
+	Use:   "pin <tag>",
+	Short: "Pin a dataset to a snapshot in the project config",
+	Long: `Pin a dataset to a snapshot, created with "deploifai dataset snapshot", in the project config.
+
+Like a lockfile, the pinned snapshot is what "deploifai dataset restore" and "deploifai dataset pull --locked" bring the local files to,
+so that committing the project config is enough to get the same files back later, or on another machine.
+
+Use --clear to remove the pin.
+`,
+	Args: cobra.RangeArgs(0, 1), // requires 1 arg, which is the tag of the snapshot, unless --clear is given
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		_context := ctx.GetContextValue(cmd)
+
+		if pinClear != (len(args) == 0) {
+			return errors.New("pin takes the tag of a snapshot, or --clear to remove the pin")
+		}
+
+		// get the dataset and directory path from config
+		ok, ds, _, err := getTargetDataset(cmd)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("the current directory is not initialised as a dataset, use --dataset to choose one")
+		}
+
+		if pinClear {
+			ds.Snapshot = ""
+			_context.Project.Datasets[ds.ID] = ds
+
+			cmd.Printf("Removed the pinned snapshot of dataset %s\n", ds.ID)
+			return nil
+		}
+
+		client, err := data_storage.New(cmd.Context(), _context.ServiceClientConfig.API, ds.ID, nil)
+		if err != nil {
+			return err
+		}
+
+		// the snapshot has to exist, and be readable, to be restored later
+		if _, err = loadSnapshot(client, args[0]); err != nil {
+			return err
+		}
+
+		ds.Snapshot = args[0]
+		_context.Project.Datasets[ds.ID] = ds
+
+		cmd.Printf("Pinned dataset %s to snapshot %s\n", ds.ID, ds.Snapshot)
+
+		return nil
+	},
+}
+
+func init() {
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// pinCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// pinCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	pinCmd.Flags().BoolVar(&pinClear, "clear", false, "remove the pinned snapshot")
+}
@@ -0,0 +1,57 @@
+package dataset
+
+import (
+	"fmt"
+	"sort"
+)
+
+type planAction string
+
+const (
+	PlanActionUpload    planAction = "upload"
+	PlanActionDownload  planAction = "download"
+	PlanActionOverwrite planAction = "overwrite"
+	PlanActionSkip      planAction = "skip"
+	PlanActionDelete    planAction = "delete"
+)
+
+// planEntry is a file that a transfer would upload, download, overwrite, skip or delete, for --dry-run.
+// To is empty for deletions.
+type planEntry struct {
+	Action planAction
+	From   string
+	To     string
+	Size   int64
+}
+
+// printPlan prints what a transfer would do, followed by the number of files and bytes for each action.
+func printPlan(entries []planEntry) {
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].From < entries[j].From
+	})
+
+	counts := map[planAction]int{}
+	sizes := map[planAction]int64{}
+
+	for _, e := range entries {
+		counts[e.Action]++
+		sizes[e.Action] += e.Size
+		if e.To == "" {
+			fmt.Printf("%-10s %s (%s)\n", e.Action, e.From, formatBytes(e.Size))
+		} else {
+			fmt.Printf("%-10s %s -> %s (%s)\n", e.Action, e.From, e.To, formatBytes(e.Size))
+		}
+	}
+
+	fmt.Printf("\nDry run, nothing was transferred:\n")
+	for _, action := range []planAction{PlanActionUpload, PlanActionDownload, PlanActionOverwrite, PlanActionSkip, PlanActionDelete} {
+		if counts[action] > 0 {
+			fmt.Printf("  %-10s %d files, %s\n", action, counts[action], formatBytes(sizes[action]))
+		}
+	}
+
+	transferCount := len(entries) - counts[PlanActionSkip] - counts[PlanActionDelete]
+	transferSize := sizes[PlanActionUpload] + sizes[PlanActionDownload] + sizes[PlanActionOverwrite]
+	fmt.Printf("  %-10s %d files, %s\n", "total", transferCount, formatBytes(transferSize))
+}
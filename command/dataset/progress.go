@@ -0,0 +1,233 @@
+package dataset
+
+import (
+	"fmt"
+	"github.com/deploifai/cli-go/command/dataset/data_storage"
+	"github.com/schollz/progressbar/v3"
+	"golang.org/x/term"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// largeFileSize is the size from which a file gets its own lines when it starts and finishes transferring
+	largeFileSize = 100 * 1024 * 1024
+	// plainProgressInterval is how often progress is printed when stdout is not a terminal
+	plainProgressInterval = 10 * time.Second
+	// barProgressInterval is how often the progress bar is updated
+	barProgressInterval = 200 * time.Millisecond
+)
+
+// transferFile is a file in a transfer. Size is 0 for operations that transfer no content, such as deletions.
+type transferFile struct {
+	Name string
+	Size int64
+}
+
+// isTerminal reports whether stdout is a terminal, otherwise progress is printed as plain lines.
+func isTerminal() bool {
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// progress reports the progress of a transfer in bytes, with the throughput and the time left,
+// on a progress bar if stdout is a terminal, or as a line printed periodically otherwise.
+// A transfer of files without content, such as deletions, reports the number of files instead.
+type progress struct {
+	description string
+
+	// traffic counts the bytes of files that are still being transferred, if it is not nil
+	traffic        *data_storage.Traffic
+	trafficAtStart int64
+
+	totalBytes     int64
+	totalFiles     int
+	completedBytes atomic.Int64
+	completedFiles atomic.Int64
+
+	start    time.Time
+	lastDone int64
+	lastTime time.Time
+
+	// bar is nil if stdout is not a terminal
+	bar   *progressbar.ProgressBar
+	mutex sync.Mutex
+
+	stop    chan struct{}
+	stopped sync.WaitGroup
+}
+
+func newProgress(description string, traffic *data_storage.Traffic, files []transferFile) *progress {
+
+	p := &progress{
+		description: description,
+		traffic:     traffic,
+		totalFiles:  len(files),
+		start:       time.Now(),
+		lastTime:    time.Now(),
+		stop:        make(chan struct{}),
+	}
+
+	for _, f := range files {
+		p.totalBytes += f.Size
+	}
+
+	if traffic != nil {
+		p.trafficAtStart = traffic.Bytes()
+	}
+
+	if isTerminal() {
+		if p.totalBytes > 0 {
+			p.bar = progressbar.NewOptions64(p.totalBytes,
+				progressbar.OptionSetDescription(p.barDescription()),
+				progressbar.OptionFullWidth(),
+				progressbar.OptionShowBytes(true),
+				progressbar.OptionSetPredictTime(true),
+				progressbar.OptionThrottle(barProgressInterval),
+			)
+		} else {
+			p.bar = progressbar.NewOptions(p.totalFiles,
+				progressbar.OptionSetDescription(description),
+				progressbar.OptionFullWidth(),
+				progressbar.OptionShowCount(),
+			)
+		}
+		_ = p.bar.RenderBlank()
+	}
+
+	interval := plainProgressInterval
+	if p.bar != nil {
+		interval = barProgressInterval
+	}
+
+	p.stopped.Add(1)
+	go func() {
+		defer p.stopped.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				p.render()
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+
+	return p
+}
+
+func (p *progress) barDescription() string {
+	return fmt.Sprintf("%s (%d/%d files)", p.description, p.completedFiles.Load(), p.totalFiles)
+}
+
+// done returns the number of bytes transferred so far, including those of files that are still being transferred.
+func (p *progress) done() int64 {
+
+	done := p.completedBytes.Load()
+
+	// the traffic also includes requests that are not for file content, so it only gives an estimate
+	if p.traffic != nil {
+		if transferred := p.traffic.Bytes() - p.trafficAtStart; transferred > done {
+			done = transferred
+		}
+	}
+
+	if done > p.totalBytes {
+		done = p.totalBytes
+	}
+
+	return done
+}
+
+func (p *progress) render() {
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.bar != nil {
+		if p.totalBytes > 0 {
+			p.bar.Describe(p.barDescription())
+			_ = p.bar.Set64(p.done())
+		} else {
+			_ = p.bar.Set64(p.completedFiles.Load())
+		}
+		return
+	}
+
+	if p.totalBytes == 0 {
+		fmt.Printf("%s: %d/%d files\n", p.description, p.completedFiles.Load(), p.totalFiles)
+		return
+	}
+
+	now := time.Now()
+	done := p.done()
+
+	var throughput float64
+	if elapsed := now.Sub(p.lastTime).Seconds(); elapsed > 0 {
+		throughput = float64(done-p.lastDone) / elapsed
+	}
+	p.lastDone, p.lastTime = done, now
+
+	eta := "unknown"
+	if average := float64(done) / now.Sub(p.start).Seconds(); average > 0 {
+		eta = (time.Duration(float64(p.totalBytes-done)/average) * time.Second).String()
+	}
+
+	fmt.Printf("%s: %s / %s (%d%%), %d/%d files, %s/s, ETA %s\n",
+		p.description, formatBytes(done), formatBytes(p.totalBytes), done*100/p.totalBytes,
+		p.completedFiles.Load(), p.totalFiles, formatBytes(int64(throughput)), eta)
+}
+
+// println prints a line above the progress bar.
+func (p *progress) println(format string, a ...interface{}) {
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.bar != nil {
+		_ = p.bar.Clear()
+	}
+	fmt.Printf(format+"\n", a...)
+}
+
+func (p *progress) startFile(f transferFile) {
+	if f.Size >= largeFileSize {
+		p.println("  started  %s (%s)", f.Name, formatBytes(f.Size))
+	}
+}
+
+func (p *progress) finishFile(f transferFile, elapsed time.Duration) {
+
+	p.completedBytes.Add(f.Size)
+	p.completedFiles.Add(1)
+
+	if f.Size >= largeFileSize {
+		p.println("  finished %s (%s) in %s, %s/s", f.Name, formatBytes(f.Size), elapsed.Round(100*time.Millisecond), formatBytes(int64(float64(f.Size)/elapsed.Seconds())))
+	}
+}
+
+// finish stops reporting progress, and prints a summary.
+func (p *progress) finish() {
+
+	close(p.stop)
+	p.stopped.Wait()
+
+	p.render()
+
+	if p.bar != nil {
+		fmt.Printf("\n")
+		return
+	}
+
+	if p.totalBytes > 0 {
+		elapsed := time.Since(p.start)
+		fmt.Printf("%s: %s in %d files in %s, %s/s\n",
+			p.description, formatBytes(p.completedBytes.Load()), p.completedFiles.Load(), elapsed.Round(time.Millisecond),
+			formatBytes(int64(float64(p.completedBytes.Load())/elapsed.Seconds())))
+	}
+}
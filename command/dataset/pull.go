@@ -4,11 +4,10 @@ Copyright © 2023 Sean Chok
 package dataset
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"github.com/deploifai/cli-go/command/ctx"
-	"github.com/deploifai/sdk-go/api/generated"
+	"github.com/deploifai/cli-go/command/dataset/data_storage"
 	"github.com/deploifai/sdk-go/service/dataset"
 	"github.com/spf13/cobra"
 	"os"
@@ -16,6 +15,18 @@ import (
 	"strings"
 )
 
+var pullForce bool
+var pullInclude []string
+var pullExclude []string
+var pullDryRun bool
+var pullDelete bool
+var pullYes bool
+var pullResume bool
+var pullConcurrency int
+var pullMaxBandwidth string
+var pullContinueOnError bool
+var pullLocked bool
+
 // pullCmd represents the pull command
 var pullCmd = &cobra.Command{
 	Use:   "pull [<path>...]",
@@ -27,22 +38,84 @@ Use the command "deploifai dataset init" to do that.
 
 Each <path> can be a directory or a file.
 If no <path> is specified, the current directory is used.
+
+Only objects that are missing locally, or that differ from the local files, are downloaded.
+Use --force to download and overwrite every file.
+
+Use --include and --exclude to select objects with glob patterns, which are matched against paths relative to the dataset directory.
+"**" matches any number of directories, e.g. --include "train/**/*.parquet" or --exclude "raw/**".
+
+Use --dry-run to list what would be downloaded, and to which local paths, without downloading anything.
+
+Use --delete to also delete local files that have no object in the dataset, so that the local directory mirrors the dataset.
+Files that are ignored, or excluded by --include or --exclude, are never deleted.
+The files to delete are listed and have to be confirmed, unless --yes is given.
+
+Pulled files are logged in a journal as they are downloaded, so if a pull is interrupted, running it again continues where it stopped.
+A file is downloaded to a temporary file next to it, which replaces it only once the download is complete,
+so an interrupted download never leaves a partial file behind.
+Use --resume to continue the interrupted pull with the paths it was given, from anywhere in the dataset directory.
+
+Use --concurrency to set how many files are transferred at the same time, and --max-bandwidth to limit the bandwidth
+of all transfers together, e.g. --max-bandwidth 50MB/s. Their defaults can be set in the [transfer] section of the config file,
+as concurrency and maxBandwidth.
+
+A file that fails is retried 3 times, waiting longer before every retry. If it still fails, the pull stops,
+or with --continue-on-error, carries on with the other files. The files that failed are listed at the end.
+The exit code is 1 if the pull failed, or 2 if some files were transferred but others failed.
+
+Use --locked to pull the snapshot that the dataset is pinned to with "deploifai dataset pin" instead, as "deploifai dataset checkout" does.
 `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 
 		_context := ctx.GetContextValue(cmd)
 
 		// get the dataset and directory path from config
-		ok, ds, datasetDirPath, err := getDataset(*_context.Project)
+		ok, ds, datasetDirPath, err := getTargetDataset(cmd)
 		if err != nil {
 			return err
 		}
 		if !ok {
-			return errors.New("the current directory is not initialised as a dataset")
+			return errors.New("the current directory is not initialised as a dataset, use --dataset to choose one")
+		}
+
+		if pullLocked {
+			if len(args) > 0 {
+				return errors.New("--locked pulls the whole pinned snapshot, it does not take paths")
+			}
+			if ds.Snapshot == "" {
+				return errors.New("the dataset is not pinned to a snapshot, use 'deploifai dataset pin' to pin it")
+			}
+
+			opts, err := newTransferOptions(_context.Root.Transfer, pullConcurrency, pullMaxBandwidth, pullContinueOnError)
+			if err != nil {
+				return err
+			}
+
+			storageClient, err := data_storage.New(cmd.Context(), _context.ServiceClientConfig.API, ds.ID, opts.Traffic)
+			if err != nil {
+				return err
+			}
+
+			return checkoutSnapshot(cmd, storageClient, opts, datasetDirPath, ds.Snapshot, pullYes, pullDryRun)
+		}
+
+		j, err := loadJournal(datasetDirPath)
+		if err != nil {
+			return err
+		}
+
+		if pullResume {
+			if len(args) > 0 {
+				return errors.New("--resume continues the interrupted pull with the paths it was given, it does not take paths")
+			}
+			if args, err = getResumeArgs(j, datasetDirPath, "pull"); err != nil {
+				return err
+			}
 		}
 
 		// get the destination absolute paths from args
-		destAbsPaths, err := getAbsPaths(args)
+		destAbsPaths, err := getAbsPaths(datasetDirPath, args)
 		if err != nil {
 			return err
 		}
@@ -60,15 +133,47 @@ If no <path> is specified, the current directory is used.
 			return err
 		}
 
-		client := dataset.NewFromConfig(*_context.ServiceClientConfig)
+		opts, err := newTransferOptions(_context.Root.Transfer, pullConcurrency, pullMaxBandwidth, pullContinueOnError)
+		if err != nil {
+			return err
+		}
+
+		storageClient, err := data_storage.New(cmd.Context(), _context.ServiceClientConfig.API, ds.ID, opts.Traffic)
+		if err != nil {
+			return err
+		}
 
-		ok, objectTypes, invalid, err := verifyRemoteObjectPrefixes(cmd.Context(), *client, ds.ID, args, remoteObjectPrefixes)
+		ok, objectTypes, invalid, err := verifyRemoteObjectPrefixes(storageClient, args, remoteObjectPrefixes)
 		if err != nil {
 			return err
 		} else if !ok {
 			return errors.New(fmt.Sprintf("no objects found in paths: %s", strings.Join(invalid, ", ")))
 		}
 
+		filter, err := newPathFilter(pullInclude, pullExclude)
+		if err != nil {
+			return err
+		}
+
+		matcher := newIgnoreMatcher(datasetDirPath)
+		datasetDirs := getDatasetDirs(*_context.Project)
+
+		m, _, err := loadManifest(datasetDirPath)
+		if err != nil {
+			cmd.Printf("Warning: %s, comparing files by size and modification time only\n", err)
+		}
+
+		if n := j.replay(m); j.Operation == "pull" {
+			cmd.Printf("Resuming the interrupted pull, %d files were already pulled\n", n)
+		}
+
+		if !pullDryRun {
+			if err := j.start("pull", toSlashAll(remoteObjectPrefixes)); err != nil {
+				return err
+			}
+			defer j.close()
+		}
+
 		for i, path := range destAbsPaths {
 			destRelPath := "."
 			objectType := ObjectTypeDirectory
@@ -76,12 +181,28 @@ If no <path> is specified, the current directory is used.
 				destRelPath = args[i]
 				objectType = objectTypes[i]
 			}
-			if err = pull(cmd.Context(), *client, ds.ID, objectType, destRelPath, path, remoteObjectPrefixes[i]); err != nil {
+			err = pull(storageClient, opts, m, j, matcher, filter, datasetDirPath, datasetDirs, objectType, destRelPath, path, remoteObjectPrefixes[i])
+			if pullDryRun {
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
+			// save the files that were pulled, even if some failed, so that they are not pulled again
+			if saveErr := m.save(); err == nil {
+				err = saveErr
+			}
+			if err != nil {
 				return err
 			}
 		}
 
-		return nil
+		if pullDryRun {
+			return nil
+		}
+
+		return j.finish(storageClient)
 	},
 }
 
@@ -95,6 +216,18 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	// pullCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	pullCmd.Flags().BoolVarP(&pullForce, "force", "f", false, "download every file, overwriting local files even if they are unchanged")
+	pullCmd.Flags().StringArrayVar(&pullInclude, "include", nil, "only pull objects that match this glob pattern, can be repeated")
+	pullCmd.Flags().StringArrayVar(&pullExclude, "exclude", nil, "do not pull objects that match this glob pattern, can be repeated")
+	pullCmd.Flags().BoolVar(&pullDryRun, "dry-run", false, "list the files that would be downloaded, overwritten or skipped, without downloading anything")
+	pullCmd.Flags().BoolVar(&pullDelete, "delete", false, "delete local files that have no object in the dataset")
+	pullCmd.Flags().BoolVarP(&pullYes, "yes", "y", false, "delete local files without asking for confirmation, with --delete or --locked")
+	pullCmd.Flags().BoolVar(&pullResume, "resume", false, "continue the interrupted pull with the paths it was given")
+	pullCmd.Flags().IntVar(&pullConcurrency, "concurrency", 0, "number of files downloaded at the same time (default one per CPU)")
+	pullCmd.Flags().StringVar(&pullMaxBandwidth, "max-bandwidth", "", "limit the bandwidth of all downloads together, e.g. 50MB/s")
+	pullCmd.Flags().BoolVar(&pullContinueOnError, "continue-on-error", false, "carry on with the other files after a file failed, instead of stopping")
+	pullCmd.Flags().BoolVar(&pullLocked, "locked", false, "pull the snapshot the dataset is pinned to, deleting local files that are not in it")
 }
 
 func verifyPullPaths(datasetDirPath string, args []string, paths []string) (ok bool, invalidArgs []string, err error) {
@@ -117,9 +250,9 @@ const (
 	ObjectTypeFile      objectType = 2
 )
 
-func verifyRemoteObjectPrefixes(ctx context.Context, client dataset.Client, dataStorageId string, args []string, remoteObjectPrefixes []string) (ok bool, objectTypes []objectType, invalid []string, err error) {
-
-	maxResultsPerPage := 1
+// verifyRemoteObjectPrefixes checks that every remote object prefix refers to a directory or a file in the dataset,
+// and returns which one it is. The args of the prefixes that refer to neither are returned in invalid.
+func verifyRemoteObjectPrefixes(client data_storage.Client, args []string, remoteObjectPrefixes []string) (ok bool, objectTypes []objectType, invalid []string, err error) {
 
 	for i, remoteObjectPrefix := range remoteObjectPrefixes {
 		prefix := dataset.CleanRemoteObjectPrefix(remoteObjectPrefix)
@@ -130,85 +263,244 @@ func verifyRemoteObjectPrefixes(ctx context.Context, client dataset.Client, data
 			continue
 		}
 
-		directoryPager, err := client.NewListObjectsPager(
-			ctx,
-			generated.DataStorageWhereUniqueInput{ID: &dataStorageId},
-			&dataset.ListObjectsInput{Prefix: &prefix, MaxResultsPerPage: &maxResultsPerPage},
-		)
+		objects, err := listRemoteObjects(client, remoteObjectPrefix)
 		if err != nil {
 			return false, nil, nil, err
 		}
 
-		if response, err := directoryPager.NextPage(nil); err != nil {
-			return false, nil, nil, err
-		} else if len(response.Objects) == 0 {
-
-			// check if it is a file
-			filePager, err := client.NewListObjectsPager(
-				ctx,
-				generated.DataStorageWhereUniqueInput{ID: &dataStorageId},
-				&dataset.ListObjectsInput{Prefix: &remoteObjectPrefix, MaxResultsPerPage: &maxResultsPerPage},
-			)
-			if err != nil {
-				return false, nil, nil, err
-			}
-
-			if response, err := filePager.NextPage(nil); err != nil {
-				return false, nil, nil, err
-			} else if len(response.Objects) == 1 && response.Objects[0].Key == remoteObjectPrefix {
-				objectTypes = append(objectTypes, ObjectTypeFile)
-			} else {
-				invalid = append(invalid, args[i])
-			}
-		} else {
+		// the prefix is a file if the only object it refers to has it as its key
+		key := strings.TrimSuffix(prefix, "/")
+		if _, isFile := objects[key]; isFile && len(objects) == 1 {
+			objectTypes = append(objectTypes, ObjectTypeFile)
+		} else if len(objects) > 0 {
 			objectTypes = append(objectTypes, ObjectTypeDirectory)
+		} else {
+			invalid = append(invalid, args[i])
 		}
-
 	}
 
 	return len(invalid) == 0, objectTypes, invalid, nil
 }
 
-func pull(ctx context.Context, client dataset.Client, dataStorageId string, objectType objectType, destRelPath string, destAbsPath string, remoteObjectPrefix string) error {
-
-	whereDataStorage := generated.DataStorageWhereUniqueInput{ID: &dataStorageId}
+func pull(client data_storage.Client, opts transferOptions, m *manifest, j *journal, matcher *ignoreMatcher, filter *pathFilter, datasetDirPath string, datasetDirs map[string]bool, objectType objectType, destRelPath string, destAbsPath string, remoteObjectPrefix string) error {
 
 	if objectType == ObjectTypeDirectory {
-		return pullDir(ctx, client, whereDataStorage, destRelPath, destAbsPath, remoteObjectPrefix)
+		return pullDir(client, opts, m, j, matcher, filter, datasetDirPath, datasetDirs, destRelPath, destAbsPath, remoteObjectPrefix)
 	} else if objectType == ObjectTypeFile {
-		return pullFile(ctx, client, whereDataStorage, destRelPath, destAbsPath, remoteObjectPrefix)
+		return pullFile(client, opts, m, j, filter, datasetDirPath, datasetDirs, destRelPath, destAbsPath, remoteObjectPrefix)
 	}
 
 	return nil
 }
 
-func pullDir(ctx context.Context, client dataset.Client, whereDataStorage generated.DataStorageWhereUniqueInput, destRelPath string, destAbsPath string, remoteObjectPrefix string) error {
+func pullDir(client data_storage.Client, opts transferOptions, m *manifest, j *journal, matcher *ignoreMatcher, filter *pathFilter, datasetDirPath string, datasetDirs map[string]bool, destRelPath string, destAbsPath string, remoteObjectPrefix string) error {
 
-	f := func(fileCountChan chan<- int, resultChan chan<- interface{}) error {
-		return client.DownloadDir(ctx,
-			whereDataStorage,
-			dataset.DownloadDirInput{DestAbsPath: destAbsPath, RemoteObjectPrefix: remoteObjectPrefix},
-			fileCountChan,
-			resultChan,
-			nil,
-		)
+	remoteObjects, err := listRemoteObjects(client, remoteObjectPrefix)
+	if err != nil {
+		return err
 	}
+	remoteObjects = filterObjects(filter, remoteObjects)
 
-	return runDir(f, fmt.Sprintf("%s -> %s", remoteObjectPrefix, destRelPath))
-}
-
-func pullFile(ctx context.Context, client dataset.Client, whereDataStorage generated.DataStorageWhereUniqueInput, destRelPath string, destAbsPath string, remoteObjectKey string) error {
+	objects, err := getObjectsToPull(m, datasetDirPath, datasetDirs, destAbsPath, remoteObjects)
+	if err != nil {
+		return err
+	}
 
-	f := func() error {
-		if err := os.MkdirAll(filepath.Dir(destAbsPath), 0755); err != nil {
+	// local files that --delete would delete
+	var deletions []localObject
+	if pullDelete {
+		localObjects, err := listLocalObjects(matcher, datasetDirPath, datasetDirs, destAbsPath)
+		if err != nil {
 			return err
 		}
+		deletions = getLocalObjectsToDelete(filterObjects(filter, localObjects), remoteObjects)
+	}
 
-		return client.DownloadFile(ctx, whereDataStorage, dataset.DownloadFileInput{DestAbsPath: destAbsPath, RemoteObjectKey: remoteObjectKey})
+	if pullDryRun {
+		planPull(datasetDirPath, remoteObjects, objects, deletions)
+		return nil
+	}
+
+	skipped := len(remoteObjects) - len(objects)
+
+	if len(objects) == 0 {
+		fmt.Printf("%s is up to date, skipped %d unchanged files\n", destRelPath, skipped)
+	} else if err := pullObjects(client, opts, m, j, datasetDirPath, objects, skipped, fmt.Sprintf("%s -> %s", remoteObjectPrefix, destRelPath)); err != nil {
+		return err
+	}
+
+	if len(deletions) == 0 {
+		return nil
+	}
+
+	var paths []string
+	for _, o := range deletions {
+		paths = append(paths, displayPath(o.AbsPath))
+	}
+
+	if confirmed, err := confirmDelete(paths, pullYes); err != nil {
+		return err
+	} else if !confirmed {
+		fmt.Println("Nothing was deleted")
+		return nil
+	}
+
+	return deleteLocalObjects(m, deletions, destAbsPath)
+}
+
+// pullObjects downloads objects into the dataset directory, skipped is the number of unchanged objects that were left out.
+func pullObjects(client data_storage.Client, opts transferOptions, m *manifest, j *journal, datasetDirPath string, objects []data_storage.Object, skipped int, progressBarDescription string) error {
+
+	files := make([]transferFile, len(objects))
+	for i, r := range objects {
+		files[i] = transferFile{Name: r.Key, Size: r.Size}
+	}
+
+	err := runDir(opts, progressBarDescription, files, func(i int) error {
+		return pullAndRecordObject(client, m, j, datasetDirPath, objects[i])
+	})
+
+	if skipped > 0 {
+		fmt.Printf("Skipped %d unchanged files\n", skipped)
+	}
+
+	return err
+}
+
+func pullFile(client data_storage.Client, opts transferOptions, m *manifest, j *journal, filter *pathFilter, datasetDirPath string, datasetDirs map[string]bool, destRelPath string, destAbsPath string, remoteObjectKey string) error {
+
+	remoteObjectKey = filepath.ToSlash(remoteObjectKey)
+
+	if !filter.matches(remoteObjectKey) {
+		fmt.Printf("Skipped %s, excluded by --include or --exclude\n", destRelPath)
+		return nil
+	}
+
+	remoteObject, err := getRemoteObject(client, remoteObjectKey)
+	if err != nil {
+		return err
+	}
+
+	remoteObjects := map[string]data_storage.Object{remoteObjectKey: remoteObject}
+
+	objects, err := getObjectsToPull(m, datasetDirPath, datasetDirs, destAbsPath, remoteObjects)
+	if err != nil {
+		return err
+	}
+
+	if pullDryRun {
+		planPull(datasetDirPath, remoteObjects, objects, nil)
+		return nil
+	}
+
+	if len(objects) == 0 {
+		fmt.Printf("Skipped %s, unchanged\n", destRelPath)
+		return nil
+	}
+
+	f := func() error {
+		return pullAndRecordObject(client, m, j, datasetDirPath, objects[0])
 	}
 
 	prefixMessage := fmt.Sprintf("Downloading %s -> %s ", remoteObjectKey, destRelPath)
 	finalMessage := fmt.Sprintf("Downloaded %s -> %s", remoteObjectKey, destRelPath)
 
-	return runFile(f, prefixMessage, finalMessage)
+	return runFile(opts, f, prefixMessage, finalMessage)
+}
+
+// getObjectsToPull returns the remote objects that are missing at destAbsPath, or that differ from the local files.
+// With --force, all remote objects are returned.
+func getObjectsToPull(m *manifest, datasetDirPath string, datasetDirs map[string]bool, destAbsPath string, remoteObjects map[string]data_storage.Object) ([]data_storage.Object, error) {
+
+	var objects []data_storage.Object
+
+	if pullForce {
+		for _, r := range remoteObjects {
+			objects = append(objects, r)
+		}
+		return objects, nil
+	}
+
+	// .deploifaiignore only applies to pushing, ignored files are still compared so that they are not overwritten needlessly
+	localObjects, err := listLocalObjects(nil, datasetDirPath, datasetDirs, destAbsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := compareObjects(m, localObjects, remoteObjects)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range changes {
+		if c.Type == ChangeTypeModified || c.Type == ChangeTypeDeleted {
+			objects = append(objects, *c.Remote)
+		}
+	}
+
+	return objects, nil
+}
+
+// planPull prints the files that pulling objects out of remoteObjects would download, overwrite or skip,
+// and the local files that --delete would delete, for --dry-run.
+func planPull(datasetDirPath string, remoteObjects map[string]data_storage.Object, objects []data_storage.Object, deletions []localObject) {
+
+	pulled := map[string]bool{}
+	for _, r := range objects {
+		pulled[r.Key] = true
+	}
+
+	var plan []planEntry
+
+	for key, r := range remoteObjects {
+		destAbsPath := filepath.Join(datasetDirPath, filepath.FromSlash(key))
+		entry := planEntry{Action: PlanActionSkip, From: key, To: displayPath(destAbsPath), Size: r.Size}
+
+		if pulled[key] {
+			if _, err := os.Stat(destAbsPath); err == nil {
+				entry.Action = PlanActionOverwrite
+			} else {
+				entry.Action = PlanActionDownload
+			}
+		}
+
+		plan = append(plan, entry)
+	}
+
+	for _, o := range deletions {
+		plan = append(plan, planEntry{Action: PlanActionDelete, From: displayPath(o.AbsPath), Size: o.Size})
+	}
+
+	printPlan(plan)
+}
+
+// pullObject downloads a remote object into the dataset directory, and records it in the manifest.
+func pullObject(client data_storage.Client, m *manifest, datasetDirPath string, r data_storage.Object) (localObject, error) {
+
+	destAbsPath := filepath.Join(datasetDirPath, filepath.FromSlash(r.Key))
+
+	if err := client.DownloadFileVersion(r.Key, r.Version, destAbsPath); err != nil {
+		return localObject{}, err
+	}
+
+	info, err := os.Stat(destAbsPath)
+	if err != nil {
+		return localObject{}, err
+	}
+
+	o := localObject{Key: r.Key, AbsPath: destAbsPath, Size: info.Size(), ModTime: info.ModTime()}
+	m.set(o, r.MD5, r.ETag)
+
+	return o, nil
+}
+
+// pullAndRecordObject downloads a remote object like pullObject, and logs it in the journal.
+func pullAndRecordObject(client data_storage.Client, m *manifest, j *journal, datasetDirPath string, r data_storage.Object) error {
+
+	o, err := pullObject(client, m, datasetDirPath, r)
+	if err != nil {
+		return err
+	}
+
+	return j.recordObject(o, r.MD5, r.ETag)
 }
@@ -0,0 +1,43 @@
+package dataset
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVerifyRemoteObjectPrefixes(t *testing.T) {
+	client := newFakeClient()
+	client.objects["a.txt"] = []byte("a")
+	client.objects["data/b.txt"] = []byte("b")
+	client.objects["data/sub/c.txt"] = []byte("c")
+	client.objects["both"] = []byte("file")
+	client.objects["both/d.txt"] = []byte("d")
+
+	tests := []struct {
+		prefix  string
+		ok      bool
+		want    []objectType
+		invalid []string
+	}{
+		{prefix: ".", ok: true, want: []objectType{ObjectTypeDirectory}},
+		{prefix: "a.txt", ok: true, want: []objectType{ObjectTypeFile}},
+		{prefix: "data", ok: true, want: []objectType{ObjectTypeDirectory}},
+		{prefix: "data/sub", ok: true, want: []objectType{ObjectTypeDirectory}},
+		{prefix: "data/b.txt", ok: true, want: []objectType{ObjectTypeFile}},
+		// a key that is also a directory is taken as the directory
+		{prefix: "both", ok: true, want: []objectType{ObjectTypeDirectory}},
+		// a prefix of a key is neither a file nor a directory
+		{prefix: "a.t", ok: false, invalid: []string{"a.t"}},
+		{prefix: "missing", ok: false, invalid: []string{"missing"}},
+	}
+
+	for _, tt := range tests {
+		ok, objectTypes, invalid, err := verifyRemoteObjectPrefixes(client, []string{tt.prefix}, []string{tt.prefix})
+		if err != nil {
+			t.Fatalf("verifyRemoteObjectPrefixes(%q) returned an error: %v", tt.prefix, err)
+		}
+		if ok != tt.ok || !reflect.DeepEqual(objectTypes, tt.want) || !reflect.DeepEqual(invalid, tt.invalid) {
+			t.Errorf("verifyRemoteObjectPrefixes(%q) = %v, %v, %v, want %v, %v, %v", tt.prefix, ok, objectTypes, invalid, tt.ok, tt.want, tt.invalid)
+		}
+	}
+}
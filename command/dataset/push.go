@@ -4,15 +4,33 @@ Copyright © 2023 Sean Chok
 package dataset
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"github.com/deploifai/cli-go/command/ctx"
-	"github.com/deploifai/sdk-go/api/generated"
-	"github.com/deploifai/sdk-go/service/dataset"
+	"github.com/deploifai/cli-go/command/dataset/data_storage"
 	"github.com/spf13/cobra"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
+)
+
+var pushInclude []string
+var pushExclude []string
+var pushDryRun bool
+var pushDelete bool
+var pushYes bool
+var pushResume bool
+var pushConcurrency int
+var pushMaxBandwidth string
+var pushContinueOnError bool
+
+const (
+	// multipartThreshold is the size from which files are uploaded in parts, if the cloud provider supports it
+	multipartThreshold = 64 * 1024 * 1024
+	minPartSize        = 16 * 1024 * 1024
+	maxParts           = 10000
 )
 
 // pushCmd represents the push command
@@ -26,27 +44,74 @@ Use the command "deploifai dataset init" to do that.
 
 Each <path> can be a directory or a file.
 If no <path> is specified, the current directory is used.
+
+Files that have not changed since they were last pushed or pulled are skipped.
+This is tracked in a manifest in the .deploifai directory of the dataset directory,
+if the manifest is missing or corrupt, all files are pushed.
+
+Files and directories listed in .deploifaiignore files are not pushed, these files use the same syntax as .gitignore files.
+Use --include and --exclude to select files with glob patterns, which are matched against paths relative to the dataset directory.
+"**" matches any number of directories, e.g. --include "train/**/*.parquet" or --exclude "raw/**".
+
+Use --delete to also delete objects in the dataset that have no local file, so that the dataset mirrors the local directory.
+Objects that are ignored, or excluded by --include or --exclude, are never deleted.
+The objects to delete are listed and have to be confirmed, unless --yes is given.
+
+Pushed files are logged in a journal as they are uploaded, so if a push is interrupted, running it again continues where it stopped.
+Large files are uploaded in parts where the cloud provider supports it, and an interrupted upload continues from the last part.
+Use --resume to continue the interrupted push with the paths it was given, from anywhere in the dataset directory.
+
+Use --concurrency to set how many files are transferred at the same time, and --max-bandwidth to limit the bandwidth
+of all transfers together, e.g. --max-bandwidth 50MB/s. Their defaults can be set in the [transfer] section of the config file,
+as concurrency and maxBandwidth.
+
+A file that fails is retried 3 times, waiting longer before every retry. If it still fails, the push stops,
+or with --continue-on-error, carries on with the other files. The files that failed are listed at the end.
+The exit code is 1 if the push failed, or 2 if some files were transferred but others failed.
 `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 
 		_context := ctx.GetContextValue(cmd)
 
 		// get the dataset and directory path from config
-		ok, ds, datasetDirPath, err := getDataset(*_context.Project)
+		ok, ds, datasetDirPath, err := getTargetDataset(cmd)
 		if err != nil {
 			return err
 		}
 		if !ok {
-			return errors.New("the current directory is not initialised as a dataset")
+			return errors.New("the current directory is not initialised as a dataset, use --dataset to choose one")
+		}
+
+		j, err := loadJournal(datasetDirPath)
+		if err != nil {
+			return err
+		}
+
+		if pushResume {
+			if len(args) > 0 {
+				return errors.New("--resume continues the interrupted push with the paths it was given, it does not take paths")
+			}
+			if args, err = getResumeArgs(j, datasetDirPath, "push"); err != nil {
+				return err
+			}
 		}
 
 		// get the source absolute paths from args
-		srcAbsPaths, err := getAbsPaths(args)
+		srcAbsPaths, err := getAbsPaths(datasetDirPath, args)
 		if err != nil {
 			return err
 		}
 
-		if ok, invalidArgs, err := verifyPushPaths(datasetDirPath, args, srcAbsPaths); err != nil {
+		matcher := newIgnoreMatcher(datasetDirPath)
+		datasetDirs := getDatasetDirs(*_context.Project)
+
+		filter, err := newPathFilter(pushInclude, pushExclude)
+		if err != nil {
+			return err
+		}
+
+		ok, invalidArgs, ignored, err := verifyPushPaths(matcher, datasetDirPath, args, srcAbsPaths)
+		if err != nil {
 			return err
 		} else if !ok {
 			return errors.New(fmt.Sprintf("invalid paths: %s", strings.Join(invalidArgs, ", ")))
@@ -58,19 +123,65 @@ If no <path> is specified, the current directory is used.
 			return err
 		}
 
-		client := dataset.NewFromConfig(*_context.ServiceClientConfig)
+		opts, err := newTransferOptions(_context.Root.Transfer, pushConcurrency, pushMaxBandwidth, pushContinueOnError)
+		if err != nil {
+			return err
+		}
+
+		client, err := data_storage.New(cmd.Context(), _context.ServiceClientConfig.API, ds.ID, opts.Traffic)
+		if err != nil {
+			return err
+		}
+
+		m, ok, err := loadManifest(datasetDirPath)
+		if err != nil {
+			cmd.Printf("Warning: %s, pushing all files\n", err)
+		} else if !ok {
+			cmd.Println("No dataset manifest found, pushing all files")
+		}
+
+		if n := j.replay(m); j.Operation == "push" {
+			cmd.Printf("Resuming the interrupted push, %d files were already pushed\n", n)
+		}
+
+		if !pushDryRun {
+			if err := j.start("push", toSlashAll(remoteObjectPrefixes)); err != nil {
+				return err
+			}
+			defer j.close()
+		}
 
 		for i, path := range srcAbsPaths {
 			srcRelPath := "."
 			if len(args) > 0 {
 				srcRelPath = args[i]
 			}
-			if err = push(cmd.Context(), *client, ds.ID, srcRelPath, path, remoteObjectPrefixes[i]); err != nil {
+			if ignored[i] {
+				cmd.Printf("Warning: %s is ignored by %s, skipping\n", srcRelPath, ignoreFilename)
+				continue
+			}
+			err = push(client, opts, m, j, matcher, filter, datasetDirPath, datasetDirs, srcRelPath, path, remoteObjectPrefixes[i])
+			if pushDryRun {
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
+			// save the files that were pushed, even if some failed, so that they are not pushed again
+			if saveErr := m.save(); err == nil {
+				err = saveErr
+			}
+			if err != nil {
 				return err
 			}
 		}
 
-		return nil
+		if pushDryRun {
+			return nil
+		}
+
+		return j.finish(client)
 	},
 }
 
@@ -84,71 +195,363 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	// pushCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	pushCmd.Flags().StringArrayVar(&pushInclude, "include", nil, "only push files that match this glob pattern, can be repeated")
+	pushCmd.Flags().StringArrayVar(&pushExclude, "exclude", nil, "do not push files that match this glob pattern, can be repeated")
+	pushCmd.Flags().BoolVar(&pushDryRun, "dry-run", false, "list the files that would be uploaded, overwritten or skipped, without uploading anything")
+	pushCmd.Flags().BoolVar(&pushDelete, "delete", false, "delete objects in the dataset that have no local file")
+	pushCmd.Flags().BoolVarP(&pushYes, "yes", "y", false, "delete objects without asking for confirmation, with --delete")
+	pushCmd.Flags().BoolVar(&pushResume, "resume", false, "continue the interrupted push with the paths it was given")
+	pushCmd.Flags().IntVar(&pushConcurrency, "concurrency", 0, "number of files uploaded at the same time (default one per CPU)")
+	pushCmd.Flags().StringVar(&pushMaxBandwidth, "max-bandwidth", "", "limit the bandwidth of all uploads together, e.g. 50MB/s")
+	pushCmd.Flags().BoolVar(&pushContinueOnError, "continue-on-error", false, "carry on with the other files after a file failed, instead of stopping")
 }
 
-func verifyPushPaths(datasetDirPath string, args []string, paths []string) (ok bool, invalidArgs []string, err error) {
+// verifyPushPaths checks that paths exist in the dataset directory, and reports which of them are ignored by matcher.
+func verifyPushPaths(matcher *ignoreMatcher, datasetDirPath string, args []string, paths []string) (ok bool, invalidArgs []string, ignored []bool, err error) {
 	for i, path := range paths {
 		// check if path exists
-		if _, err := os.Stat(path); os.IsNotExist(err) {
+		fileInfo, err := os.Stat(path)
+		if os.IsNotExist(err) {
 			invalidArgs = append(invalidArgs, args[i])
+			continue
 		} else if err != nil {
-			return false, nil, err
+			return false, nil, nil, err
 		}
 
 		// check if path is a subdirectory of datasetDirPath
 		if ok, err := isSubDir(datasetDirPath, path); err != nil {
-			return false, nil, err
+			return false, nil, nil, err
 		} else if !ok {
 			invalidArgs = append(invalidArgs, args[i])
+			continue
+		}
+
+		// check if path is ignored
+		rel, err := filepath.Rel(datasetDirPath, path)
+		if err != nil {
+			return false, nil, nil, err
 		}
+		isIgnored, err := matcher.isIgnored(filepath.ToSlash(rel), fileInfo.IsDir())
+		if err != nil {
+			return false, nil, nil, err
+		}
+		ignored = append(ignored, isIgnored)
 	}
 
-	return len(invalidArgs) == 0, invalidArgs, nil
+	return len(invalidArgs) == 0, invalidArgs, ignored, nil
 }
 
-func push(ctx context.Context, client dataset.Client, dataStorageId string, srcRelPath string, srcAbsPath string, remoteObjectPrefix string) error {
+func push(client data_storage.Client, opts transferOptions, m *manifest, j *journal, matcher *ignoreMatcher, filter *pathFilter, datasetDirPath string, datasetDirs map[string]bool, srcRelPath string, srcAbsPath string, remoteObjectPrefix string) error {
 
 	fileInfo, err := os.Stat(srcAbsPath)
 	if err != nil {
 		return err
 	}
 
-	whereDataStorage := generated.DataStorageWhereUniqueInput{ID: &dataStorageId}
-
 	if fileInfo.IsDir() {
 		// upload directory
-		return pushDir(ctx, client, whereDataStorage, srcRelPath, srcAbsPath, remoteObjectPrefix)
+		return pushDir(client, opts, m, j, matcher, filter, datasetDirPath, datasetDirs, srcRelPath, srcAbsPath, remoteObjectPrefix)
 	} else {
 		// upload file
-		return pushFile(ctx, client, whereDataStorage, srcRelPath, srcAbsPath, remoteObjectPrefix)
+		return pushFile(client, opts, m, j, matcher, filter, datasetDirPath, datasetDirs, srcRelPath, srcAbsPath, remoteObjectPrefix)
 	}
 }
 
-func pushDir(ctx context.Context, client dataset.Client, whereDataStorage generated.DataStorageWhereUniqueInput, srcRelPath string, srcAbsPath string, remoteObjectPrefix string) error {
+func pushDir(client data_storage.Client, opts transferOptions, m *manifest, j *journal, matcher *ignoreMatcher, filter *pathFilter, datasetDirPath string, datasetDirs map[string]bool, srcRelPath string, srcAbsPath string, remoteObjectPrefix string) error {
 
-	f := func(fileCountChan chan<- int, resultChan chan<- interface{}) error {
-		return client.UploadDir(ctx,
-			whereDataStorage,
-			dataset.UploadDirInput{SrcAbsPath: srcAbsPath, RemoteObjectPrefix: remoteObjectPrefix},
-			fileCountChan,
-			resultChan,
-			nil,
-		)
+	localObjects, err := listLocalObjects(matcher, datasetDirPath, datasetDirs, srcAbsPath)
+	if err != nil {
+		return err
+	}
+	localObjects = filterObjects(filter, localObjects)
+
+	if pushDryRun {
+		return planPush(client, m, matcher, filter, localObjects, remoteObjectPrefix, pushDelete)
 	}
 
-	return runDir(f, fmt.Sprintf("%s -> %s", srcRelPath, remoteObjectPrefix))
+	// files that have not changed since they were last transferred are skipped without reading them
+	var candidates []localObject
+	for _, o := range localObjects {
+		if _, ok := m.lookup(o); !ok {
+			candidates = append(candidates, o)
+		}
+	}
 
+	var skipped atomic.Int64
+	skipped.Add(int64(len(localObjects) - len(candidates)))
+
+	if len(candidates) == 0 {
+		fmt.Printf("%s is up to date, skipped %d unchanged files\n", srcRelPath, skipped.Load())
+	} else if err := pushObjects(client, opts, m, j, candidates, &skipped, fmt.Sprintf("%s -> %s", srcRelPath, remoteObjectPrefix)); err != nil {
+		return err
+	}
+
+	if !pushDelete {
+		return nil
+	}
+
+	remoteObjects, err := listRemoteObjects(client, remoteObjectPrefix)
+	if err != nil {
+		return err
+	}
+
+	objects, err := getRemoteObjectsToDelete(matcher, localObjects, filterObjects(filter, remoteObjects))
+	if err != nil {
+		return err
+	} else if len(objects) == 0 {
+		return nil
+	}
+
+	var keys []string
+	for _, r := range objects {
+		keys = append(keys, r.Key)
+	}
+
+	if confirmed, err := confirmDelete(keys, pushYes); err != nil {
+		return err
+	} else if !confirmed {
+		fmt.Println("Nothing was deleted")
+		return nil
+	}
+
+	return deleteRemoteObjects(client, opts, m, objects, fmt.Sprintf("Deleting from %s", remoteObjectPrefix))
+}
+
+// pushObjects uploads the candidates that have changed since they were last transferred, counting the others in skipped.
+func pushObjects(client data_storage.Client, opts transferOptions, m *manifest, j *journal, candidates []localObject, skipped *atomic.Int64, progressBarDescription string) error {
+
+	files := make([]transferFile, len(candidates))
+	for i, o := range candidates {
+		files[i] = transferFile{Name: o.Key, Size: o.Size}
+	}
+
+	err := runDir(opts, progressBarDescription, files, func(i int) error {
+		if uploaded, err := pushObject(client, m, j, candidates[i]); err != nil {
+			return err
+		} else if !uploaded {
+			skipped.Add(1)
+		}
+		return nil
+	})
+
+	if n := skipped.Load(); n > 0 {
+		fmt.Printf("Skipped %d unchanged files\n", n)
+	}
+
+	return err
 }
 
-func pushFile(ctx context.Context, client dataset.Client, whereDataStorage generated.DataStorageWhereUniqueInput, srcRelPath string, srcAbsPath string, remoteObjectKey string) error {
+func pushFile(client data_storage.Client, opts transferOptions, m *manifest, j *journal, matcher *ignoreMatcher, filter *pathFilter, datasetDirPath string, datasetDirs map[string]bool, srcRelPath string, srcAbsPath string, remoteObjectKey string) error {
 
-	f := func() error {
-		return client.UploadFile(ctx, whereDataStorage, dataset.UploadFileInput{SrcAbspath: srcAbsPath, RemoteObjectKey: remoteObjectKey})
+	localObjects, err := listLocalObjects(matcher, datasetDirPath, datasetDirs, srcAbsPath)
+	if err != nil {
+		return err
 	}
 
-	prefixMessage := fmt.Sprintf("Uploading %s -> %s ", srcRelPath, remoteObjectKey)
-	finalMessage := fmt.Sprintf("Uploaded %s -> %s", srcRelPath, remoteObjectKey)
+	o, ok := localObjects[filepath.ToSlash(remoteObjectKey)]
+	if !ok {
+		return errors.New(fmt.Sprintf("%s is not a regular file", srcRelPath))
+	}
 
-	return runFile(f, prefixMessage, finalMessage)
+	if !filter.matches(o.Key) {
+		fmt.Printf("Skipped %s, excluded by --include or --exclude\n", srcRelPath)
+		return nil
+	}
+
+	if pushDryRun {
+		return planPush(client, m, matcher, filter, localObjects, remoteObjectKey, false)
+	}
+
+	if _, ok := m.lookup(o); ok {
+		fmt.Printf("Skipped %s, unchanged\n", srcRelPath)
+		return nil
+	}
+
+	uploaded := false
+
+	f := func() (err error) {
+		uploaded, err = pushObject(client, m, j, o)
+		return err
+	}
+
+	prefixMessage := fmt.Sprintf("Uploading %s -> %s ", srcRelPath, o.Key)
+	finalMessage := fmt.Sprintf("Uploaded %s -> %s", srcRelPath, o.Key)
+
+	if err := runFile(opts, f, prefixMessage, finalMessage); err != nil {
+		return err
+	}
+
+	if !uploaded {
+		fmt.Printf("Skipped %s, unchanged\n", srcRelPath)
+	}
+
+	return nil
+}
+
+// isPushNeeded reports whether a local file has changed since it was last transferred.
+// The checksum of the file is returned if it had to be computed.
+func isPushNeeded(m *manifest, o localObject) (needed bool, checksum string, err error) {
+
+	if _, ok := m.lookup(o); ok {
+		return false, "", nil
+	}
+
+	checksum, err = fileMD5(o.AbsPath)
+	if err != nil {
+		return false, "", err
+	}
+
+	// the file may have been touched without its content changing
+	if recorded, ok := m.recorded(o.Key); ok && recorded.MD5 == checksum {
+		return false, checksum, nil
+	}
+
+	return true, checksum, nil
+}
+
+// planPush prints the files that a push of localObjects would upload, overwrite or skip, for --dry-run.
+// With mirror, it also prints the remote objects that --delete would delete.
+func planPush(client data_storage.Client, m *manifest, matcher *ignoreMatcher, filter *pathFilter, localObjects map[string]localObject, remoteObjectPrefix string, mirror bool) error {
+
+	remoteObjects, err := listRemoteObjects(client, remoteObjectPrefix)
+	if err != nil {
+		return err
+	}
+
+	var plan []planEntry
+
+	for key, o := range localObjects {
+		entry := planEntry{Action: PlanActionUpload, From: displayPath(o.AbsPath), To: key, Size: o.Size}
+
+		if needed, _, err := isPushNeeded(m, o); err != nil {
+			return err
+		} else if !needed {
+			entry.Action = PlanActionSkip
+		} else if _, ok := remoteObjects[key]; ok {
+			entry.Action = PlanActionOverwrite
+		}
+
+		plan = append(plan, entry)
+	}
+
+	if mirror {
+		objects, err := getRemoteObjectsToDelete(matcher, localObjects, filterObjects(filter, remoteObjects))
+		if err != nil {
+			return err
+		}
+		for _, r := range objects {
+			plan = append(plan, planEntry{Action: PlanActionDelete, From: r.Key, Size: r.Size})
+		}
+	}
+
+	printPlan(plan)
+
+	return nil
+}
+
+// pushObject uploads a local file unless its content is the same as when it was last transferred,
+// and records it in the manifest and the journal.
+func pushObject(client data_storage.Client, m *manifest, j *journal, o localObject) (uploaded bool, err error) {
+
+	needed, checksum, err := isPushNeeded(m, o)
+	if err != nil {
+		return false, err
+	}
+
+	if !needed {
+		if recorded, ok := m.recorded(o.Key); ok && checksum != "" {
+			m.set(o, checksum, recorded.ETag)
+		}
+		return false, nil
+	}
+
+	eTag, err := uploadObject(client, j, o, checksum)
+	if err != nil {
+		return false, err
+	}
+
+	m.set(o, checksum, eTag)
+
+	return true, j.recordObject(o, checksum, eTag)
+}
+
+// uploadObject uploads a local file, in parts that are logged in the journal if the file is large
+// and the cloud provider supports it, continuing an upload of the file that was interrupted.
+func uploadObject(client data_storage.Client, j *journal, o localObject, checksum string) (eTag string, err error) {
+
+	multipartClient, ok := client.(data_storage.MultipartClient)
+	if !ok || o.Size < multipartThreshold {
+		return client.UploadFile(data_storage.UploadFileInput{SrcAbsPath: o.AbsPath, RemoteObjectKey: o.Key, MD5: checksum})
+	}
+
+	if u, ok := j.staleUpload(o); ok {
+		_ = multipartClient.AbortMultipartUpload(o.Key, u.UploadID)
+	}
+
+	if u, ok := j.upload(o); ok {
+		if eTag, err := uploadParts(multipartClient, j, o, checksum, u); err == nil {
+			return eTag, nil
+		}
+		// the upload may have expired on the cloud provider's side, so it starts over
+		_ = multipartClient.AbortMultipartUpload(o.Key, u.UploadID)
+	}
+
+	uploadId, err := multipartClient.CreateMultipartUpload(o.Key)
+	if err != nil {
+		return "", err
+	}
+
+	u, err := j.startUpload(o, uploadId)
+	if err != nil {
+		return "", err
+	}
+
+	return uploadParts(multipartClient, j, o, checksum, u)
+}
+
+// partSizeFor returns the size of the parts that content of the given size is uploaded in,
+// which is the minimum part size unless that would take more than maxParts parts.
+func partSizeFor(size int64) int64 {
+	if size > minPartSize*maxParts {
+		return (size + maxParts - 1) / maxParts
+	}
+	return minPartSize
+}
+
+// uploadParts uploads the parts of a large file that have not been uploaded yet, and assembles them into the object.
+func uploadParts(client data_storage.MultipartClient, j *journal, o localObject, checksum string, u *journalUpload) (eTag string, err error) {
+
+	file, err := os.Open(o.AbsPath)
+	if err != nil {
+		return "", err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	partSize := partSizeFor(o.Size)
+
+	var parts []data_storage.Part
+
+	for n, offset := 1, int64(0); offset < o.Size; n, offset = n+1, offset+partSize {
+		size := partSize
+		if offset+size > o.Size {
+			size = o.Size - offset
+		}
+
+		partETag, ok := u.Parts[n]
+		if !ok {
+			if partETag, err = client.UploadPart(o.Key, u.UploadID, n, io.NewSectionReader(file, offset, size), size); err != nil {
+				return "", err
+			}
+			if err := j.recordPart(o.Key, u, n, partETag); err != nil {
+				return "", err
+			}
+		}
+
+		parts = append(parts, data_storage.Part{Number: n, ETag: partETag})
+	}
 
+	return client.CompleteMultipartUpload(o.Key, u.UploadID, parts, checksum)
 }
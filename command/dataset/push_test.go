@@ -0,0 +1,95 @@
+package dataset
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPushObject(t *testing.T) {
+	modTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		content  string
+		recorded *manifestEntry
+		uploaded bool
+	}{
+		{name: "new file", content: "hello", uploaded: true},
+		{
+			// the file was touched, so it is read, but its content is the same
+			name:     "touched without changes",
+			content:  "hello",
+			recorded: &manifestEntry{Size: 5, ModTime: modTime.Add(-time.Hour), MD5: testMD5("hello"), ETag: `"recorded"`},
+			uploaded: false,
+		},
+		{
+			name:     "changed",
+			content:  "hello!",
+			recorded: &manifestEntry{Size: 5, ModTime: modTime.Add(-time.Hour), MD5: testMD5("hello")},
+			uploaded: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			absPath := filepath.Join(t.TempDir(), "a.txt")
+			writeTestFile(t, absPath, tt.content)
+			o := localObject{Key: "data/a.txt", AbsPath: absPath, Size: int64(len(tt.content)), ModTime: modTime}
+
+			m := &manifest{Files: map[string]manifestEntry{}}
+			if tt.recorded != nil {
+				m.Files[o.Key] = *tt.recorded
+			}
+
+			dir := t.TempDir()
+			j, err := loadJournal(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := j.start("push", nil); err != nil {
+				t.Fatal(err)
+			}
+
+			client := newFakeClient()
+			uploaded, err := pushObject(client, m, j, o)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if uploaded != tt.uploaded {
+				t.Errorf("pushObject() = %v, want %v", uploaded, tt.uploaded)
+			}
+			if tt.uploaded && string(client.objects[o.Key]) != tt.content {
+				t.Errorf("uploaded %q, want %q", client.objects[o.Key], tt.content)
+			}
+			if !tt.uploaded && client.uploads != 0 {
+				t.Errorf("uploaded %d files, want none", client.uploads)
+			}
+
+			// an uploaded file is logged so that an interrupted push does not upload it again
+			j.close()
+			logged, err := loadJournal(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := logged.objects[o.Key]; ok != tt.uploaded {
+				t.Errorf("logged in the journal: %v, want %v", ok, tt.uploaded)
+			}
+
+			// the file is recorded as it is now either way, so the next push skips it without reading it
+			entry, ok := m.lookup(o)
+			if !ok || entry.MD5 != testMD5(tt.content) {
+				t.Fatalf("manifest lookup() = %+v, %v, want the checksum of the file", entry, ok)
+			}
+			// the ETag is the one of the upload, or the one recorded before if nothing was uploaded
+			wantETag := `"recorded"`
+			if tt.uploaded {
+				wantETag = `"` + testMD5(tt.content) + `"`
+			}
+			if entry.ETag != wantETag {
+				t.Errorf("manifest ETag = %s, want %s", entry.ETag, wantETag)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+/*
+Copyright © 2023 Sean Chok
+*/
+package dataset
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/deploifai/cli-go/command/ctx"
+	"github.com/deploifai/cli-go/command/dataset/data_storage"
+	"github.com/spf13/cobra"
+	"io"
+)
+
+// putCmd represents the put command
+var putCmd = &cobra.Command{
+	Use:   "put <path> -",
+	Short: "Upload stdin to a file of a dataset",
+	Long: `Stream stdin to a file of a dataset, without writing it to the local filesystem,
+e.g. "gen_data | deploifai dataset put out/part-001.jsonl -".
+The file in the dataset is replaced if it exists, and the local file at <path>, if any, is not changed.
+
+<path> is resolved like the paths of "deploifai dataset pull", relative to the current directory,
+and does not have to exist locally. Use "deploifai dataset push" to upload local files.
+
+Large uploads are sent in parts of 16 MiB where the cloud provider supports it, and each part is retried if it fails.
+`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		if args[1] != "-" {
+			return errors.New(fmt.Sprintf("put only uploads stdin, given as -, use 'deploifai dataset push %s' to upload a local file", args[1]))
+		}
+
+		_context := ctx.GetContextValue(cmd)
+
+		// get the dataset and directory path from config
+		ok, ds, datasetDirPath, err := getTargetDataset(cmd)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("the current directory is not initialised as a dataset, use --dataset to choose one")
+		}
+
+		remoteObjectKey, err := getRemoteObjectKey(datasetDirPath, args[0])
+		if err != nil {
+			return err
+		}
+		if isReservedKey(remoteObjectKey) || remoteObjectKey == localStateDirName {
+			return errors.New(fmt.Sprintf("cannot upload to %s, %s is reserved for the CLI", args[0], reservedKeyPrefix))
+		}
+
+		opts, err := newTransferOptions(_context.Root.Transfer, 0, "", false)
+		if err != nil {
+			return err
+		}
+
+		client, err := data_storage.New(cmd.Context(), _context.ServiceClientConfig.API, ds.ID, opts.Traffic)
+		if err != nil {
+			return err
+		}
+
+		size, err := uploadStream(client, opts, remoteObjectKey, cmd.InOrStdin(), -1)
+		if err != nil {
+			return err
+		}
+
+		cmd.Printf("Uploaded %s from stdin to %s\n", formatBytes(size), remoteObjectKey)
+
+		return nil
+	},
+}
+
+func init() {
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// putCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// putCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
+
+// uploadStream uploads everything read from reader to an object, and returns its size.
+// sizeHint is the size of the content if it is known up front, which makes the parts large enough for it, or -1.
+// Content that fits in a part is uploaded at once. Larger content is uploaded in parts if the cloud provider supports it,
+// holding one part in memory at a time, or streamed otherwise.
+func uploadStream(client data_storage.Client, opts transferOptions, remoteObjectKey string, reader io.Reader, sizeHint int64) (int64, error) {
+
+	hash := md5.New()
+	reader = io.TeeReader(reader, hash)
+
+	partSize := partSizeFor(sizeHint)
+	part := make([]byte, partSize)
+
+	n, err := io.ReadFull(reader, part)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = retryTask(opts, func() error {
+			_, err := client.UploadStream(remoteObjectKey, bytes.NewReader(part[:n]), int64(n))
+			return err
+		})
+		return int64(n), err
+	} else if err != nil {
+		return 0, err
+	}
+
+	multipartClient, ok := client.(data_storage.MultipartClient)
+	if !ok {
+		counter := &countingReader{reader: io.MultiReader(bytes.NewReader(part), reader)}
+		_, err = client.UploadStream(remoteObjectKey, counter, -1)
+		return counter.n, err
+	}
+
+	uploadId, err := multipartClient.CreateMultipartUpload(remoteObjectKey)
+	if err != nil {
+		return 0, err
+	}
+
+	abort := func(err error) (int64, error) {
+		_ = multipartClient.AbortMultipartUpload(remoteObjectKey, uploadId)
+		return 0, err
+	}
+
+	var parts []data_storage.Part
+	var size int64
+
+	for number := 1; ; number++ {
+		if number > maxParts {
+			return abort(errors.New(fmt.Sprintf("%s is too large to upload, it has more than %d parts of %s", remoteObjectKey, maxParts, formatBytes(partSize))))
+		}
+
+		var eTag string
+		err = retryTask(opts, func() (err error) {
+			eTag, err = multipartClient.UploadPart(remoteObjectKey, uploadId, number, bytes.NewReader(part[:n]), int64(n))
+			return err
+		})
+		if err != nil {
+			return abort(err)
+		}
+		parts = append(parts, data_storage.Part{Number: number, ETag: eTag})
+		size += int64(n)
+
+		n, err = io.ReadFull(reader, part)
+		if err == io.EOF {
+			break
+		} else if err != nil && err != io.ErrUnexpectedEOF {
+			return abort(err)
+		}
+	}
+
+	if _, err = multipartClient.CompleteMultipartUpload(remoteObjectKey, uploadId, parts, hex.EncodeToString(hash.Sum(nil))); err != nil {
+		return abort(err)
+	}
+
+	return size, nil
+}
+
+// countingReader counts the bytes read from reader.
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,155 @@
+package dataset
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/deploifai/cli-go/command/dataset/data_storage"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeMultipartClient is a fakeClient that also uploads in parts, and fails the first partFailures parts it is sent.
+type fakeMultipartClient struct {
+	*fakeClient
+
+	partFailures int
+	uploads      map[string]map[int][]byte
+	// sizes of the parts sent, in order, including the ones that failed
+	partSizes []int64
+	aborted   []string
+}
+
+func newFakeMultipartClient() *fakeMultipartClient {
+	return &fakeMultipartClient{fakeClient: newFakeClient(), uploads: map[string]map[int][]byte{}}
+}
+
+func (f *fakeMultipartClient) CreateMultipartUpload(remoteObjectKey string) (string, error) {
+	uploadId := fmt.Sprintf("upload-%d", len(f.uploads)+1)
+	f.uploads[uploadId] = map[int][]byte{}
+	return uploadId, nil
+}
+
+func (f *fakeMultipartClient) UploadPart(remoteObjectKey string, uploadId string, partNumber int, reader io.ReadSeeker, size int64) (string, error) {
+	f.partSizes = append(f.partSizes, size)
+	if f.partFailures > 0 {
+		f.partFailures--
+		return "", errors.New("connection reset")
+	}
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+	f.uploads[uploadId][partNumber] = content
+
+	return fmt.Sprintf("part-%d", partNumber), nil
+}
+
+func (f *fakeMultipartClient) CompleteMultipartUpload(remoteObjectKey string, uploadId string, parts []data_storage.Part, md5 string) (string, error) {
+	var content []byte
+	for i, p := range parts {
+		if p.Number != i+1 || p.ETag != fmt.Sprintf("part-%d", p.Number) {
+			return "", errors.New(fmt.Sprintf("unexpected part %+v", p))
+		}
+		content = append(content, f.uploads[uploadId][p.Number]...)
+	}
+	if md5 != testMD5(string(content)) {
+		return "", errors.New("checksum mismatch")
+	}
+	f.objects[remoteObjectKey] = content
+
+	return fmt.Sprintf("%s-%d", md5, len(parts)), nil
+}
+
+func (f *fakeMultipartClient) AbortMultipartUpload(remoteObjectKey string, uploadId string) error {
+	f.aborted = append(f.aborted, uploadId)
+	delete(f.uploads, uploadId)
+	return nil
+}
+
+func TestUploadStream(t *testing.T) {
+	defer func(delay time.Duration) {
+		transferRetryDelay = delay
+	}(transferRetryDelay)
+	transferRetryDelay = time.Millisecond
+
+	tests := []struct {
+		name         string
+		size         int64
+		sizeHint     int64
+		multipart    bool
+		partFailures int
+		// sizes passed to UploadStream, and to UploadPart
+		streamSizes []int64
+		partSizes   []int64
+		wantErr     bool
+	}{
+		{name: "empty", size: 0, sizeHint: -1, multipart: true, streamSizes: []int64{0}},
+		{name: "smaller than a part", size: 1000, sizeHint: -1, multipart: true, streamSizes: []int64{1000}},
+		{name: "one byte short of a part", size: minPartSize - 1, sizeHint: -1, multipart: true, streamSizes: []int64{minPartSize - 1}},
+		{name: "exactly a part", size: minPartSize, sizeHint: -1, multipart: true, partSizes: []int64{minPartSize}},
+		{name: "a part and a byte", size: minPartSize + 1, sizeHint: -1, multipart: true, partSizes: []int64{minPartSize, 1}},
+		{name: "exactly two parts", size: 2 * minPartSize, sizeHint: -1, multipart: true, partSizes: []int64{minPartSize, minPartSize}},
+		{
+			// a hint too large for maxParts parts of the minimum size makes the parts larger
+			name: "parts sized for the hint", size: minPartSize + 2, sizeHint: minPartSize*maxParts + maxParts, multipart: true,
+			partSizes: []int64{minPartSize + 1, 1},
+		},
+		{name: "failed parts are retried", size: minPartSize + 1, sizeHint: -1, multipart: true, partFailures: 2, partSizes: []int64{minPartSize, minPartSize, minPartSize, 1}},
+		{name: "a part that keeps failing aborts the upload", size: minPartSize + 1, sizeHint: -1, multipart: true, partFailures: 100, partSizes: []int64{minPartSize, minPartSize, minPartSize, minPartSize}, wantErr: true},
+		{name: "streamed without parts", size: minPartSize + 1, sizeHint: -1, multipart: false, streamSizes: []int64{-1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := make([]byte, tt.size)
+			for i := range content {
+				content[i] = byte(i % 251)
+			}
+
+			var client data_storage.Client
+			var fake *fakeClient
+			var multipart *fakeMultipartClient
+			if tt.multipart {
+				multipart = newFakeMultipartClient()
+				multipart.partFailures = tt.partFailures
+				client, fake = multipart, multipart.fakeClient
+			} else {
+				fake = newFakeClient()
+				client = fake
+			}
+
+			size, err := uploadStream(client, transferOptions{Retries: transferRetries}, "out/a.bin", bytes.NewReader(content), tt.sizeHint)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("uploadStream() returned error %v, want error %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(fake.streamSizes, tt.streamSizes) {
+				t.Errorf("UploadStream sizes = %v, want %v", fake.streamSizes, tt.streamSizes)
+			}
+			if multipart != nil && !reflect.DeepEqual(multipart.partSizes, tt.partSizes) {
+				t.Errorf("UploadPart sizes = %v, want %v", multipart.partSizes, tt.partSizes)
+			}
+
+			if tt.wantErr {
+				if _, ok := fake.objects["out/a.bin"]; ok {
+					t.Error("the object was uploaded after an error")
+				}
+				if multipart != nil && len(multipart.aborted) != 1 {
+					t.Errorf("%d uploads were aborted, want 1", len(multipart.aborted))
+				}
+				return
+			}
+
+			if size != tt.size {
+				t.Errorf("uploadStream() = %d, want %d", size, tt.size)
+			}
+			if !bytes.Equal(fake.objects["out/a.bin"], content) {
+				t.Errorf("the uploaded object has %d bytes that differ from the %d bytes of the content", len(fake.objects["out/a.bin"]), len(content))
+			}
+		})
+	}
+}
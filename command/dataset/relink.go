@@ -0,0 +1,118 @@
+/*
+Copyright © 2023 Sean Chok
+*/
+package dataset
+
+import (
+	"errors"
+	"fmt"
+	"github.com/deploifai/cli-go/command/command_config/project_config"
+	"github.com/deploifai/cli-go/command/ctx"
+	"github.com/spf13/cobra"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// relinkCmd represents the relink command
+var relinkCmd = &cobra.Command{
+	Use:   "relink <dir>",
+	Short: "Use another directory for a dataset",
+	Long: `Point a dataset at another directory, e.g. after its directory was renamed or moved.
+
+The dataset is the one chosen with --dataset, or the one that contains the current directory.
+Without either, the dataset whose directory no longer exists is used, if there is only one.
+
+The files are not moved, <dir> must already exist.
+`,
+	Args: cobra.ExactArgs(1), // requires exactly 1 arg, which is the new directory
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		_context := ctx.GetContextValue(cmd)
+
+		ds, err := getRelinkDataset(cmd)
+		if err != nil {
+			return err
+		}
+
+		dirAbsPath, err := filepath.Abs(args[0])
+		if err != nil {
+			return err
+		}
+
+		if info, err := os.Stat(dirAbsPath); err != nil {
+			return err
+		} else if !info.IsDir() {
+			return errors.New(fmt.Sprintf("%s is not a directory", args[0]))
+		}
+
+		relativeDirectory, err := filepath.Rel(filepath.Dir(_context.Project.ConfigFile), dirAbsPath)
+		if err != nil {
+			return err
+		}
+		relativeDirectory = filepath.ToSlash(relativeDirectory)
+
+		for id, d := range _context.Project.Datasets {
+			if id != ds.ID && path.Clean(d.LocalDirectory) == relativeDirectory {
+				return errors.New(fmt.Sprintf("%s is already used by dataset %s", args[0], id))
+			}
+		}
+
+		if err = saveDirInConfig(_context.Project, ds.ID, dirAbsPath); err != nil {
+			return err
+		}
+
+		cmd.Printf("Dataset %s is now linked to %s\n", ds.ID, relativeDirectory)
+
+		return nil
+	},
+}
+
+func init() {
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// relinkCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// relinkCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
+
+// getRelinkDataset returns the dataset chosen with --dataset, the one of the current directory,
+// or the only dataset whose directory no longer exists.
+func getRelinkDataset(cmd *cobra.Command) (project_config.Dataset, error) {
+
+	_context := ctx.GetContextValue(cmd)
+
+	if datasetFlag != "" {
+		ds, _, err := getFlagDataset(cmd)
+		return ds, err
+	}
+
+	if ok, ds, _, err := getDataset(*_context.Project); err != nil {
+		return ds, err
+	} else if ok {
+		return ds, nil
+	}
+
+	stale, err := getStaleDatasets(*_context.Project)
+	if err != nil {
+		return project_config.Dataset{}, err
+	}
+
+	switch len(stale) {
+	case 0:
+		return project_config.Dataset{}, errors.New("the current directory is not initialised as a dataset, use --dataset to choose one")
+	case 1:
+		return stale[0], nil
+	default:
+		ids := make([]string, len(stale))
+		for i, d := range stale {
+			ids[i] = d.ID
+		}
+		return project_config.Dataset{}, errors.New(fmt.Sprintf("the directories of datasets %s no longer exist, use --dataset to choose one", strings.Join(ids, ", ")))
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright © 2023 Sean Chok
+*/
+package dataset
+
+import (
+	"errors"
+	"fmt"
+	"github.com/deploifai/cli-go/command/command_config/project_config"
+	"github.com/deploifai/cli-go/command/ctx"
+	"github.com/deploifai/cli-go/command/dataset/data_storage"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+var restoreYes bool
+var restoreDryRun bool
+
+// restoreCmd represents the restore command
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Bring every dataset in the project to its pinned snapshot",
+	Long: `Bring the local files of every dataset in the project config to the snapshot it is pinned to with "deploifai dataset pin",
+as "deploifai dataset checkout" does, creating the directories of the datasets if they do not exist.
+Datasets that are not pinned are left as they are.
+
+Use --dataset to only restore one dataset.
+
+Local files that are not in a snapshot are deleted, and have to be confirmed, unless --yes is given.
+Use --dry-run to list the files that would be downloaded or deleted, without changing anything.
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		_context := ctx.GetContextValue(cmd)
+
+		if err := verifyLocalDirectories(*_context.Project); err != nil {
+			return err
+		}
+
+		var datasets []project_config.Dataset
+
+		if datasetFlag != "" {
+			ds, _, err := getFlagDataset(cmd)
+			if err != nil {
+				return err
+			}
+			if ds.Snapshot == "" {
+				return errors.New(fmt.Sprintf("dataset %s is not pinned to a snapshot, use 'deploifai dataset pin' to pin it", datasetFlag))
+			}
+			datasets = append(datasets, ds)
+		} else {
+			for _, ds := range _context.Project.Datasets {
+				datasets = append(datasets, ds)
+			}
+			sort.Slice(datasets, func(i, j int) bool {
+				return datasets[i].LocalDirectory < datasets[j].LocalDirectory
+			})
+		}
+
+		if len(datasets) == 0 {
+			return errors.New("there are no datasets in this project, use 'deploifai dataset init' to initialise one")
+		}
+
+		projectDir := filepath.Dir(_context.Project.ConfigFile)
+
+		opts, err := newTransferOptions(_context.Root.Transfer, 0, "", false)
+		if err != nil {
+			return err
+		}
+
+		for _, ds := range datasets {
+			if ds.Snapshot == "" {
+				cmd.Printf("Skipped %s, dataset %s is not pinned to a snapshot\n", ds.LocalDirectory, ds.ID)
+				continue
+			}
+
+			cmd.Printf("Restoring %s to snapshot %s\n", ds.LocalDirectory, ds.Snapshot)
+
+			datasetDirPath := filepath.Join(projectDir, filepath.FromSlash(ds.LocalDirectory))
+			if !restoreDryRun {
+				if err := os.MkdirAll(datasetDirPath, 0755); err != nil {
+					return err
+				}
+			}
+
+			client, err := data_storage.New(cmd.Context(), _context.ServiceClientConfig.API, ds.ID, opts.Traffic)
+			if err != nil {
+				return err
+			}
+
+			if err = checkoutSnapshot(cmd, client, opts, datasetDirPath, ds.Snapshot, restoreYes, restoreDryRun); err != nil {
+				return fmt.Errorf("failed to restore %s: %w", ds.LocalDirectory, err)
+			}
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// restoreCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// restoreCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	restoreCmd.Flags().BoolVarP(&restoreYes, "yes", "y", false, "delete local files that are not in the snapshots without asking for confirmation")
+	restoreCmd.Flags().BoolVar(&restoreDryRun, "dry-run", false, "list the files that would be downloaded or deleted, without changing anything")
+}
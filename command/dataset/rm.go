@@ -0,0 +1,192 @@
+/*
+Copyright © 2023 Sean Chok
+*/
+package dataset
+
+import (
+	"errors"
+	"fmt"
+	"github.com/deploifai/cli-go/command/ctx"
+	"github.com/deploifai/cli-go/command/dataset/data_storage"
+	"github.com/spf13/cobra"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var rmRecursive bool
+var rmYes bool
+var rmDryRun bool
+
+// rmCmd represents the rm command
+var rmCmd = &cobra.Command{
+	Use:   "rm <path>...",
+	Short: "Delete files or directories from a dataset",
+	Long: `Delete files or directories from a dataset.
+Local files are not deleted.
+
+This requires the local directory to be initialised as a dataset first.
+Use the command "deploifai dataset init" to do that.
+
+Each <path> is resolved the same way as for "deploifai dataset pull", and can be a file or a directory in the dataset.
+Use -r to delete directories, and all the objects in them.
+
+The objects to delete are listed and have to be confirmed, unless --yes is given.
+Use --dry-run to list the objects that would be deleted, without deleting anything.
+`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		_context := ctx.GetContextValue(cmd)
+
+		// get the dataset and directory path from config
+		ok, ds, datasetDirPath, err := getTargetDataset(cmd)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("the current directory is not initialised as a dataset, use --dataset to choose one")
+		}
+
+		absPaths, err := getAbsPaths(datasetDirPath, args)
+		if err != nil {
+			return err
+		}
+
+		// verify the paths, which do not have to exist locally
+		if ok, invalidArgs, err := verifyPullPaths(datasetDirPath, args, absPaths); err != nil {
+			return err
+		} else if !ok {
+			return errors.New(fmt.Sprintf("invalid paths: %s", strings.Join(invalidArgs, ", ")))
+		}
+
+		remoteObjectPrefixes, err := getRemoteObjectPrefixes(datasetDirPath, absPaths)
+		if err != nil {
+			return err
+		}
+
+		opts, err := newTransferOptions(_context.Root.Transfer, 0, "", false)
+		if err != nil {
+			return err
+		}
+
+		storageClient, err := data_storage.New(cmd.Context(), _context.ServiceClientConfig.API, ds.ID, opts.Traffic)
+		if err != nil {
+			return err
+		}
+
+		ok, objectTypes, invalid, err := verifyRemoteObjectPrefixes(storageClient, args, remoteObjectPrefixes)
+		if err != nil {
+			return err
+		} else if !ok {
+			return errors.New(fmt.Sprintf("no objects found in paths: %s", strings.Join(invalid, ", ")))
+		}
+
+		var directories []string
+		for i, t := range objectTypes {
+			if t == ObjectTypeDirectory && !rmRecursive {
+				directories = append(directories, args[i])
+			}
+		}
+		if len(directories) > 0 {
+			return errors.New(fmt.Sprintf("use -r to delete directories: %s", strings.Join(directories, ", ")))
+		}
+
+		objects, err := getObjectsToRemove(storageClient, objectTypes, remoteObjectPrefixes)
+		if err != nil {
+			return err
+		}
+
+		if len(objects) == 0 {
+			cmd.Println("No objects to delete")
+			return nil
+		}
+
+		if rmDryRun {
+			var plan []planEntry
+			for _, r := range objects {
+				plan = append(plan, planEntry{Action: PlanActionDelete, From: r.Key, Size: r.Size})
+			}
+			printPlan(plan)
+			return nil
+		}
+
+		var keys []string
+		for _, r := range objects {
+			keys = append(keys, r.Key)
+		}
+
+		if confirmed, err := confirmDelete(keys, rmYes); err != nil {
+			return err
+		} else if !confirmed {
+			cmd.Println("Nothing was deleted")
+			return nil
+		}
+
+		// deleted objects are forgotten in the manifest, so that their local files are pushed again if they still exist
+		m, _, _ := loadManifest(datasetDirPath)
+
+		err = deleteRemoteObjects(storageClient, opts, m, objects, fmt.Sprintf("Deleting %s", strings.Join(args, ", ")))
+
+		if saveErr := m.save(); err == nil {
+			err = saveErr
+		}
+
+		return err
+	},
+}
+
+func init() {
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// rmCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// rmCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	rmCmd.Flags().BoolVarP(&rmRecursive, "recursive", "r", false, "delete directories and all the objects in them")
+	rmCmd.Flags().BoolVarP(&rmYes, "yes", "y", false, "delete objects without asking for confirmation")
+	rmCmd.Flags().BoolVar(&rmDryRun, "dry-run", false, "list the objects that would be deleted, without deleting anything")
+}
+
+// getObjectsToRemove returns the objects that remoteObjectPrefixes refer to, without duplicates, sorted by key.
+func getObjectsToRemove(client data_storage.Client, objectTypes []objectType, remoteObjectPrefixes []string) ([]data_storage.Object, error) {
+
+	seen := map[string]bool{}
+	var objects []data_storage.Object
+
+	for i, remoteObjectPrefix := range remoteObjectPrefixes {
+
+		var remoteObjects map[string]data_storage.Object
+
+		if objectTypes[i] == ObjectTypeDirectory {
+			var err error
+			if remoteObjects, err = listRemoteObjects(client, remoteObjectPrefix); err != nil {
+				return nil, err
+			}
+		} else {
+			key := filepath.ToSlash(remoteObjectPrefix)
+			r, err := getRemoteObject(client, key)
+			if err != nil {
+				return nil, err
+			}
+			remoteObjects = map[string]data_storage.Object{key: r}
+		}
+
+		for key, r := range remoteObjects {
+			if !seen[key] {
+				seen[key] = true
+				objects = append(objects, r)
+			}
+		}
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+
+	return objects, nil
+}
@@ -0,0 +1,255 @@
+/*
+Copyright © 2023 Sean Chok
+*/
+package dataset
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/deploifai/cli-go/command/ctx"
+	"github.com/deploifai/cli-go/command/dataset/data_storage"
+	"github.com/spf13/cobra"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// reservedKeyPrefix is the prefix of the objects that the CLI keeps for itself in a dataset,
+	// which are left out of the files of the dataset, like the local state directory is.
+	reservedKeyPrefix = localStateDirName + "/"
+	// snapshotKeyPrefix is the prefix of the manifests of snapshots in a dataset
+	snapshotKeyPrefix = reservedKeyPrefix + "snapshots/"
+)
+
+var snapshotTagPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// snapshot is the manifest of the objects of a dataset at a point in time.
+// Once written, it is never changed.
+type snapshot struct {
+	Tag       string           `json:"tag"`
+	CreatedAt time.Time        `json:"createdAt"`
+	Objects   []snapshotObject `json:"objects"`
+}
+
+type snapshotObject struct {
+	Key  string `json:"key"`
+	Size int64  `json:"size"`
+	ETag string `json:"etag,omitempty"`
+	MD5  string `json:"md5,omitempty"`
+
+	// Version is the version of the object, if the cloud provider keeps versions of objects
+	Version string `json:"version,omitempty"`
+	// Generation is the generation of the object, if the cloud provider numbers the writes of objects
+	Generation string `json:"generation,omitempty"`
+}
+
+var snapshotTag string
+
+// snapshotCmd represents the snapshot command
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot --tag <tag>",
+	Short: "Record the current state of a dataset under a tag",
+	Long: `Record the files in a dataset as they are now, under a tag, to get them back later with "deploifai dataset checkout <tag>".
+
+The snapshot is a manifest of the keys, sizes, checksums and versions of the objects in the dataset,
+stored in the dataset itself under ` + snapshotKeyPrefix + `. A tag cannot be reused.
+Local changes that have not been pushed are not part of the snapshot.
+
+Objects are only kept as they were if the cloud storage keeps versions of objects, e.g. with versioning enabled on the bucket.
+Otherwise, the snapshot can only be checked out while the objects in it are unchanged.
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		_context := ctx.GetContextValue(cmd)
+
+		if !snapshotTagPattern.MatchString(snapshotTag) {
+			return errors.New(fmt.Sprintf("invalid tag: %s, a tag can only contain letters, digits, '.', '_' and '-', and must start with a letter or a digit", snapshotTag))
+		}
+
+		// get the dataset and directory path from config
+		ok, ds, _, err := getTargetDataset(cmd)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("the current directory is not initialised as a dataset, use --dataset to choose one")
+		}
+
+		client, err := data_storage.New(cmd.Context(), _context.ServiceClientConfig.API, ds.ID, nil)
+		if err != nil {
+			return err
+		}
+
+		if exists, err := snapshotExists(client, snapshotTag); err != nil {
+			return err
+		} else if exists {
+			return errors.New(fmt.Sprintf("snapshot %s already exists", snapshotTag))
+		}
+
+		remoteObjects, err := listSnapshotObjects(client)
+		if err != nil {
+			return err
+		}
+
+		s := snapshot{Tag: snapshotTag, CreatedAt: time.Now().UTC(), Objects: make([]snapshotObject, 0, len(remoteObjects))}
+		for _, r := range remoteObjects {
+			s.Objects = append(s.Objects, snapshotObject{Key: r.Key, Size: r.Size, ETag: r.ETag, MD5: r.MD5, Version: r.Version, Generation: r.Generation})
+		}
+		sort.Slice(s.Objects, func(i, j int) bool {
+			return s.Objects[i].Key < s.Objects[j].Key
+		})
+
+		if err = saveSnapshot(client, s); err != nil {
+			return err
+		}
+
+		cmd.Printf("Created snapshot %s of %d files, %s\n", s.Tag, len(s.Objects), formatBytes(s.size()))
+
+		return nil
+	},
+}
+
+func init() {
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// snapshotCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// snapshotCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	snapshotCmd.Flags().StringVarP(&snapshotTag, "tag", "t", "", "tag of the snapshot, e.g. v3")
+	_ = snapshotCmd.MarkFlagRequired("tag")
+}
+
+// listSnapshotObjects lists the objects of a dataset for a snapshot, with their versions
+// if the cloud provider only reports them with a separate listing.
+func listSnapshotObjects(client data_storage.Client) (map[string]data_storage.Object, error) {
+
+	lister, ok := client.(data_storage.VersionLister)
+	if !ok {
+		return listRemoteObjects(client, "")
+	}
+
+	list, err := lister.ListObjectVersions("")
+	if err != nil {
+		return nil, err
+	}
+
+	return remoteObjectsByKey(list, ""), nil
+}
+
+// isReservedKey reports whether key is an object that the CLI keeps for itself.
+func isReservedKey(key string) bool {
+	return strings.HasPrefix(key, reservedKeyPrefix)
+}
+
+func snapshotKey(tag string) string {
+	return snapshotKeyPrefix + tag + ".json"
+}
+
+func (s snapshot) size() int64 {
+
+	var size int64
+	for _, o := range s.Objects {
+		size += o.Size
+	}
+
+	return size
+}
+
+// remoteObjects returns the objects of the snapshot, keyed by their keys.
+func (s snapshot) remoteObjects() map[string]data_storage.Object {
+
+	objects := map[string]data_storage.Object{}
+	for _, o := range s.Objects {
+		objects[o.Key] = data_storage.Object{Key: o.Key, Size: o.Size, ETag: o.ETag, MD5: o.MD5, Version: o.Version, Generation: o.Generation}
+	}
+
+	return objects
+}
+
+func snapshotExists(client data_storage.Client, tag string) (bool, error) {
+
+	list, err := client.ListObjects(snapshotKey(tag))
+	if err != nil {
+		return false, err
+	}
+
+	for _, o := range list {
+		if o.Key == snapshotKey(tag) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func saveSnapshot(client data_storage.Client, s snapshot) error {
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = client.UploadStream(snapshotKey(s.Tag), bytes.NewReader(data), int64(len(data)))
+
+	return err
+}
+
+func loadSnapshot(client data_storage.Client, tag string) (snapshot, error) {
+
+	var s snapshot
+
+	if !snapshotTagPattern.MatchString(tag) {
+		return s, errors.New(fmt.Sprintf("invalid tag: %s", tag))
+	}
+
+	if exists, err := snapshotExists(client, tag); err != nil {
+		return s, err
+	} else if !exists {
+		return s, errors.New(fmt.Sprintf("snapshot %s does not exist, use 'deploifai dataset snapshots' to list the snapshots", tag))
+	}
+
+	reader, err := client.OpenObject(snapshotKey(tag))
+	if err != nil {
+		return s, err
+	}
+	defer func(reader io.ReadCloser) {
+		_ = reader.Close()
+	}(reader)
+
+	if err = json.NewDecoder(reader).Decode(&s); err != nil {
+		return s, errors.New(fmt.Sprintf("snapshot %s is corrupt: %s", tag, err))
+	}
+
+	return s, nil
+}
+
+// listSnapshotTags returns the tags of the snapshots in a dataset, sorted.
+func listSnapshotTags(client data_storage.Client) ([]string, error) {
+
+	list, err := client.ListObjects(snapshotKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []string
+	for _, o := range list {
+		tag := strings.TrimSuffix(strings.TrimPrefix(o.Key, snapshotKeyPrefix), ".json")
+		if strings.HasSuffix(o.Key, ".json") && snapshotTagPattern.MatchString(tag) {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+
+	return tags, nil
+}
@@ -0,0 +1,62 @@
+package dataset
+
+import (
+	"github.com/deploifai/cli-go/command/dataset/data_storage"
+	"testing"
+)
+
+// fakeVersionedClient is a fakeClient that lists the versions of objects separately, as S3 does.
+type fakeVersionedClient struct {
+	*fakeClient
+}
+
+func (f *fakeVersionedClient) ListObjectVersions(prefix string) ([]data_storage.Object, error) {
+
+	objects, err := f.ListObjects(prefix)
+	for i := range objects {
+		objects[i].Version = "v-" + objects[i].Key
+	}
+
+	return objects, err
+}
+
+func TestListSnapshotObjects(t *testing.T) {
+	tests := []struct {
+		name      string
+		versioned bool
+	}{
+		{name: "versions listed with the objects", versioned: false},
+		{name: "versions listed separately", versioned: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeClient()
+			fake.objects["a.txt"] = []byte("a")
+			fake.objects[snapshotKey("v1")] = []byte("{}")
+
+			var client data_storage.Client = fake
+			if tt.versioned {
+				client = &fakeVersionedClient{fakeClient: fake}
+			}
+
+			objects, err := listSnapshotObjects(client)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the manifests of other snapshots are not part of a snapshot
+			if len(objects) != 1 {
+				t.Fatalf("listSnapshotObjects() = %v, want only a.txt", objects)
+			}
+
+			want := ""
+			if tt.versioned {
+				want = "v-a.txt"
+			}
+			if objects["a.txt"].Version != want {
+				t.Errorf("a.txt has version %q, want %q", objects["a.txt"].Version, want)
+			}
+		})
+	}
+}
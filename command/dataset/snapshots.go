@@ -0,0 +1,105 @@
+/*
+Copyright © 2023 Sean Chok
+*/
+package dataset
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/deploifai/cli-go/command/ctx"
+	"github.com/deploifai/cli-go/command/dataset/data_storage"
+	"github.com/spf13/cobra"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+var snapshotsJSON bool
+
+type snapshotInList struct {
+	Tag       string    `json:"tag"`
+	CreatedAt time.Time `json:"createdAt"`
+	Files     int       `json:"files"`
+	Size      int64     `json:"size"`
+}
+
+// snapshotsCmd represents the snapshots command
+var snapshotsCmd = &cobra.Command{
+	Use:   "snapshots",
+	Short: "List the snapshots of a dataset",
+	Long: `List the snapshots of a dataset that were created with "deploifai dataset snapshot", oldest first.
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		_context := ctx.GetContextValue(cmd)
+
+		// get the dataset and directory path from config
+		ok, ds, _, err := getTargetDataset(cmd)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("the current directory is not initialised as a dataset, use --dataset to choose one")
+		}
+
+		client, err := data_storage.New(cmd.Context(), _context.ServiceClientConfig.API, ds.ID, nil)
+		if err != nil {
+			return err
+		}
+
+		tags, err := listSnapshotTags(client)
+		if err != nil {
+			return err
+		}
+
+		snapshots := make([]snapshotInList, 0, len(tags))
+		for _, tag := range tags {
+			s, err := loadSnapshot(client, tag)
+			if err != nil {
+				return err
+			}
+			snapshots = append(snapshots, snapshotInList{Tag: tag, CreatedAt: s.CreatedAt, Files: len(s.Objects), Size: s.size()})
+		}
+
+		sort.SliceStable(snapshots, func(i, j int) bool {
+			return snapshots[i].CreatedAt.Before(snapshots[j].CreatedAt)
+		})
+
+		if snapshotsJSON {
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+
+			return encoder.Encode(snapshots)
+		}
+
+		if len(snapshots) == 0 {
+			cmd.Println("No snapshots found in this dataset")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+
+		_, _ = fmt.Fprintln(w, "TAG\tCREATED\tFILES\tSIZE")
+		for _, s := range snapshots {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", s.Tag, s.CreatedAt.Local().Format(time.DateTime), s.Files, formatBytes(s.Size))
+		}
+
+		return w.Flush()
+	},
+}
+
+func init() {
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// snapshotsCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// snapshotsCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	snapshotsCmd.Flags().BoolVar(&snapshotsJSON, "json", false, "print the snapshots as JSON")
+}
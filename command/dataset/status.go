@@ -0,0 +1,174 @@
+/*
+Copyright © 2023 Sean Chok
+*/
+package dataset
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/deploifai/cli-go/command/ctx"
+	"github.com/deploifai/cli-go/command/dataset/data_storage"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+var statusJSON bool
+
+type statusEntry struct {
+	Path       string     `json:"path"`
+	Status     changeType `json:"status"`
+	LocalSize  *int64     `json:"localSize,omitempty"`
+	RemoteSize *int64     `json:"remoteSize,omitempty"`
+}
+
+// statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:   "status [<path>...]",
+	Short: "Show the differences between local files and a dataset",
+	Long: `Show the files that differ between the local filesystem and a dataset.
+
+Files are compared by size, and by checksum when the cloud provider reports one for the remote object,
+or by the checksums of their parts for files uploaded in parts.
+Local files ignored by .deploifaiignore files are left out.
+A file is "added" if it only exists locally, "modified" if it differs from the remote object,
+and "deleted" if it only exists in the dataset.
+
+This requires the local directory to be initialised as a dataset first.
+Use the command "deploifai dataset init" to do that.
+
+Each <path> can be a directory or a file.
+If no <path> is specified, the current directory is used.
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		_context := ctx.GetContextValue(cmd)
+
+		// get the dataset and directory path from config
+		ok, ds, datasetDirPath, err := getTargetDataset(cmd)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("the current directory is not initialised as a dataset, use --dataset to choose one")
+		}
+
+		absPaths, err := getAbsPaths(datasetDirPath, args)
+		if err != nil {
+			return err
+		}
+
+		// verify the paths, which do not have to exist locally
+		if ok, invalidArgs, err := verifyPullPaths(datasetDirPath, args, absPaths); err != nil {
+			return err
+		} else if !ok {
+			return errors.New(fmt.Sprintf("invalid paths: %s", strings.Join(invalidArgs, ", ")))
+		}
+
+		remoteObjectPrefixes, err := getRemoteObjectPrefixes(datasetDirPath, absPaths)
+		if err != nil {
+			return err
+		}
+
+		client, err := data_storage.New(cmd.Context(), _context.ServiceClientConfig.API, ds.ID, nil)
+		if err != nil {
+			return err
+		}
+
+		// the manifest only speeds up checksums, so a missing or corrupt one is not an error here
+		m, _, _ := loadManifest(datasetDirPath)
+		matcher := newIgnoreMatcher(datasetDirPath)
+		datasetDirs := getDatasetDirs(*_context.Project)
+
+		var changes []change
+		for i, path := range absPaths {
+			c, err := getChanges(client, m, matcher, datasetDirPath, datasetDirs, path, remoteObjectPrefixes[i])
+			if err != nil {
+				return err
+			}
+			changes = append(changes, c...)
+		}
+
+		if statusJSON {
+			return printStatusJSON(cmd, changes)
+		}
+
+		printStatus(cmd, changes)
+
+		return nil
+	},
+}
+
+func init() {
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// statusCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// statusCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	statusCmd.Flags().BoolVar(&statusJSON, "json", false, "print the differences as JSON")
+}
+
+// getChanges compares the local files at absPath with the remote objects under remoteObjectPrefix.
+func getChanges(client data_storage.Client, m *manifest, matcher *ignoreMatcher, datasetDirPath string, datasetDirs map[string]bool, absPath string, remoteObjectPrefix string) ([]change, error) {
+
+	localObjects, err := listLocalObjects(matcher, datasetDirPath, datasetDirs, absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteObjects, err := listRemoteObjects(client, remoteObjectPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return compareObjects(m, localObjects, remoteObjects)
+}
+
+func printStatus(cmd *cobra.Command, changes []change) {
+
+	counts := map[changeType]int{}
+
+	for _, c := range changes {
+		counts[c.Type]++
+		if c.Type != ChangeTypeUnchanged {
+			cmd.Printf("%-10s %s\n", c.Type, c.Key)
+		}
+	}
+
+	if counts[ChangeTypeAdded]+counts[ChangeTypeModified]+counts[ChangeTypeDeleted] == 0 {
+		cmd.Println("Local files and dataset are in sync")
+		return
+	}
+
+	cmd.Printf("\n%d added, %d modified, %d deleted, %d unchanged\n",
+		counts[ChangeTypeAdded], counts[ChangeTypeModified], counts[ChangeTypeDeleted], counts[ChangeTypeUnchanged])
+}
+
+func printStatusJSON(cmd *cobra.Command, changes []change) error {
+
+	entries := make([]statusEntry, 0, len(changes))
+
+	for _, c := range changes {
+		if c.Type == ChangeTypeUnchanged {
+			continue
+		}
+		entry := statusEntry{Path: c.Key, Status: c.Type}
+		if c.Local != nil {
+			entry.LocalSize = &c.Local.Size
+		}
+		if c.Remote != nil {
+			entry.RemoteSize = &c.Remote.Size
+		}
+		entries = append(entries, entry)
+	}
+
+	encoder := json.NewEncoder(cmd.OutOrStdout())
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(entries)
+}
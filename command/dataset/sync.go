@@ -0,0 +1,446 @@
+/*
+Copyright © 2023 Sean Chok
+*/
+package dataset
+
+import (
+	"errors"
+	"fmt"
+	"github.com/deploifai/cli-go/command/ctx"
+	"github.com/deploifai/cli-go/command/dataset/data_storage"
+	"github.com/spf13/cobra"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var syncPrefer string
+var syncDryRun bool
+
+type syncAction string
+
+const (
+	SyncActionUpload       syncAction = "upload"
+	SyncActionDownload     syncAction = "download"
+	SyncActionDeleteRemote syncAction = "delete remote"
+	SyncActionDeleteLocal  syncAction = "delete local"
+	SyncActionConflict     syncAction = "conflict"
+	// SyncActionNone is a file that is already the same on both sides, whose base snapshot only needs updating
+	SyncActionNone syncAction = "none"
+)
+
+// syncChange is what syncing does to a file, which may be missing locally, remotely, or both.
+type syncChange struct {
+	Key    string
+	Action syncAction
+	Local  *localObject
+	Remote *data_storage.Object
+}
+
+// syncCmd represents the sync command
+var syncCmd = &cobra.Command{
+	Use:   "sync [<path>...]",
+	Short: "Sync files or directories with a dataset in both directions",
+	Long: `Sync files or directories between the local filesystem and a dataset in both directions.
+
+Each file is compared with a base snapshot of the dataset taken when it was last synced,
+which is kept in the .deploifai directory of the dataset directory.
+A file that was changed, added or deleted locally since is uploaded to, or deleted from, the dataset,
+and a file that was changed, added or deleted in the dataset since is downloaded, or deleted locally.
+
+A file that was changed on both sides is a conflict, and is left as it is.
+Use --prefer local or --prefer remote to resolve conflicts by keeping the local file or the remote object.
+On the first sync there is no base snapshot, so every file that differs between the two sides is a conflict.
+
+Local files ignored by .deploifaiignore files are not synced.
+
+Synced files are logged in a journal as they are transferred, so if a sync is interrupted, running it again continues where it stopped.
+Large files are uploaded in parts, and continue from the last part that was uploaded.
+
+This requires the local directory to be initialised as a dataset first.
+Use the command "deploifai dataset init" to do that.
+
+Each <path> can be a directory or a file.
+If no <path> is specified, the current directory is used.
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		if syncPrefer != "" && syncPrefer != "local" && syncPrefer != "remote" {
+			return errors.New(fmt.Sprintf("invalid value for --prefer: %s, it must be local or remote", syncPrefer))
+		}
+
+		_context := ctx.GetContextValue(cmd)
+
+		// get the dataset and directory path from config
+		ok, ds, datasetDirPath, err := getTargetDataset(cmd)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("the current directory is not initialised as a dataset, use --dataset to choose one")
+		}
+
+		absPaths, err := getAbsPaths(datasetDirPath, args)
+		if err != nil {
+			return err
+		}
+
+		// verify the paths, which do not have to exist locally
+		if ok, invalidArgs, err := verifyPullPaths(datasetDirPath, args, absPaths); err != nil {
+			return err
+		} else if !ok {
+			return errors.New(fmt.Sprintf("invalid paths: %s", strings.Join(invalidArgs, ", ")))
+		}
+
+		remoteObjectPrefixes, err := getRemoteObjectPrefixes(datasetDirPath, absPaths)
+		if err != nil {
+			return err
+		}
+
+		opts, err := newTransferOptions(_context.Root.Transfer, 0, "", false)
+		if err != nil {
+			return err
+		}
+
+		client, err := data_storage.New(cmd.Context(), _context.ServiceClientConfig.API, ds.ID, opts.Traffic)
+		if err != nil {
+			return err
+		}
+
+		// the manifest only speeds up checksums, so a missing or corrupt one is not an error here
+		m, _, _ := loadManifest(datasetDirPath)
+		matcher := newIgnoreMatcher(datasetDirPath)
+		datasetDirs := getDatasetDirs(*_context.Project)
+
+		state, err := loadSyncState(datasetDirPath)
+		if err != nil {
+			return err
+		}
+
+		j, err := loadJournal(datasetDirPath)
+		if err != nil {
+			return err
+		}
+
+		if n := j.replay(m); j.Operation == "sync" {
+			cmd.Printf("Resuming the interrupted sync, %d files were already synced\n", n)
+		}
+
+		if !syncDryRun {
+			if err := j.start("sync", toSlashAll(remoteObjectPrefixes)); err != nil {
+				return err
+			}
+			defer j.close()
+		}
+
+		conflicts := 0
+		for i, path := range absPaths {
+			n, err := syncPath(client, opts, m, j, state, matcher, datasetDirPath, datasetDirs, path, remoteObjectPrefixes[i])
+			if syncDryRun {
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			conflicts += n
+
+			// save the files that were synced, even if some failed, so that they are not synced again
+			if saveErr := m.save(); err == nil {
+				err = saveErr
+			}
+			if saveErr := state.save(); err == nil {
+				err = saveErr
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		if syncDryRun {
+			return nil
+		}
+
+		if err := j.finish(client); err != nil {
+			return err
+		}
+
+		if conflicts > 0 {
+			return errors.New(fmt.Sprintf("%d conflicts were not synced, use --prefer local or --prefer remote to resolve them", conflicts))
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// syncCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// syncCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	syncCmd.Flags().StringVar(&syncPrefer, "prefer", "", "resolve conflicts by keeping the local file or the remote object, one of: local, remote")
+	syncCmd.Flags().BoolVar(&syncDryRun, "dry-run", false, "list the changes that would be synced, and the conflicts, without syncing anything")
+}
+
+// syncPath syncs the local files at absPath with the remote objects under remoteObjectPrefix,
+// and returns the number of conflicts that were left unresolved.
+func syncPath(client data_storage.Client, opts transferOptions, m *manifest, j *journal, state *syncState, matcher *ignoreMatcher, datasetDirPath string, datasetDirs map[string]bool, absPath string, remoteObjectPrefix string) (int, error) {
+
+	localObjects, err := listLocalObjects(matcher, datasetDirPath, datasetDirs, absPath)
+	if err != nil {
+		return 0, err
+	}
+
+	remoteObjects, err := listRemoteObjects(client, remoteObjectPrefix)
+	if err != nil {
+		return 0, err
+	}
+
+	changes, err := getSyncChanges(m, state, matcher, localObjects, remoteObjects, remoteObjectPrefix)
+	if err != nil {
+		return 0, err
+	}
+
+	var tasks []syncChange
+	var localDeletions []localObject
+	conflicts := 0
+
+	for _, c := range changes {
+		if c.Action == SyncActionNone {
+			continue
+		}
+		fmt.Printf("%-14s %s\n", c.Action, c.Key)
+
+		switch c.Action {
+		case SyncActionConflict:
+			conflicts++
+		case SyncActionDeleteLocal:
+			localDeletions = append(localDeletions, *c.Local)
+		default:
+			tasks = append(tasks, c)
+		}
+	}
+
+	if syncDryRun {
+		fmt.Printf("\nDry run, nothing was synced: %d changes, %d conflicts\n", len(tasks)+len(localDeletions), conflicts)
+		return conflicts, nil
+	}
+
+	// files that are the same on both sides only need their base snapshot updated
+	for _, c := range changes {
+		if c.Action != SyncActionNone {
+			continue
+		}
+		if c.Local == nil {
+			state.remove(c.Key)
+		} else if checksum, err := localObjectMD5(m, *c.Local); err != nil {
+			return conflicts, err
+		} else {
+			state.set(c.Key, syncStateEntry{Size: c.Local.Size, MD5: checksum, ETag: c.Remote.ETag})
+		}
+	}
+
+	if len(tasks) == 0 && len(localDeletions) == 0 {
+		fmt.Printf("%s is in sync\n", displayPath(absPath))
+		return conflicts, nil
+	}
+
+	if len(tasks) > 0 {
+		files := make([]transferFile, len(tasks))
+		for i, c := range tasks {
+			files[i] = transferFile{Name: c.Key}
+			switch c.Action {
+			case SyncActionUpload:
+				files[i].Size = c.Local.Size
+			case SyncActionDownload:
+				files[i].Size = c.Remote.Size
+			}
+		}
+
+		err := runDir(opts, fmt.Sprintf("Syncing %s", displayPath(absPath)), files, func(i int) error {
+			return applySyncChange(client, m, j, state, datasetDirPath, tasks[i])
+		})
+		if err != nil {
+			return conflicts, err
+		}
+	}
+
+	if len(localDeletions) > 0 {
+		if err := deleteLocalObjects(m, localDeletions, absPath); err != nil {
+			return conflicts, err
+		}
+		for _, o := range localDeletions {
+			state.remove(o.Key)
+		}
+	}
+
+	return conflicts, nil
+}
+
+// getSyncChanges works out a three-way change set from the local files, the remote objects and the base snapshot.
+// The changes are sorted by key.
+func getSyncChanges(m *manifest, state *syncState, matcher *ignoreMatcher, localObjects map[string]localObject, remoteObjects map[string]data_storage.Object, remoteObjectPrefix string) ([]syncChange, error) {
+
+	keys := map[string]bool{}
+	for key := range localObjects {
+		keys[key] = true
+	}
+	for key := range remoteObjects {
+		keys[key] = true
+	}
+	for _, key := range state.keys() {
+		if inRemoteObjectPrefix(key, remoteObjectPrefix) {
+			keys[key] = true
+		}
+	}
+
+	var changes []syncChange
+
+	for key := range keys {
+		// ignored files are not synced, and they are never listed locally, so they must not be taken as deleted
+		if ignored, err := matcher.isIgnored(key, false); err != nil {
+			return nil, err
+		} else if ignored {
+			continue
+		}
+
+		c := syncChange{Key: key}
+		if l, ok := localObjects[key]; ok {
+			c.Local = &l
+		}
+		if r, ok := remoteObjects[key]; ok {
+			c.Remote = &r
+		}
+
+		action, err := getSyncAction(m, state, c)
+		if err != nil {
+			return nil, err
+		}
+		c.Action = action
+
+		changes = append(changes, c)
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+
+	return changes, nil
+}
+
+func getSyncAction(m *manifest, state *syncState, c syncChange) (syncAction, error) {
+
+	if c.Local == nil && c.Remote == nil {
+		return SyncActionNone, nil
+	}
+
+	if c.Local != nil && c.Remote != nil {
+		if modified, err := isModified(m, *c.Local, *c.Remote); err != nil {
+			return "", err
+		} else if !modified {
+			return SyncActionNone, nil
+		}
+	}
+
+	base, inBase := state.get(c.Key)
+
+	localChanged := inBase != (c.Local != nil)
+	if inBase && c.Local != nil {
+		if c.Local.Size != base.Size {
+			localChanged = true
+		} else if checksum, err := localObjectMD5(m, *c.Local); err != nil {
+			return "", err
+		} else {
+			localChanged = checksum != base.MD5
+		}
+	}
+
+	remoteChanged := inBase != (c.Remote != nil)
+	if inBase && c.Remote != nil {
+		remoteChanged = c.Remote.Size != base.Size || c.Remote.ETag != base.ETag
+	}
+
+	if localChanged && remoteChanged {
+		switch syncPrefer {
+		case "local":
+			remoteChanged = false
+		case "remote":
+			localChanged = false
+		default:
+			return SyncActionConflict, nil
+		}
+	}
+
+	if localChanged {
+		if c.Local != nil {
+			return SyncActionUpload, nil
+		}
+		return SyncActionDeleteRemote, nil
+	}
+
+	if remoteChanged {
+		if c.Remote != nil {
+			return SyncActionDownload, nil
+		}
+		return SyncActionDeleteLocal, nil
+	}
+
+	// neither side changed, but they were compared as different, e.g. by modification time only
+	return SyncActionNone, nil
+}
+
+// applySyncChange uploads, downloads or deletes a remote object, records the result in the manifest and the base snapshot,
+// and logs the files it transferred in the journal.
+// Files are uploaded as push uploads them, in parts that are logged in the journal if they are large.
+func applySyncChange(client data_storage.Client, m *manifest, j *journal, state *syncState, datasetDirPath string, c syncChange) error {
+
+	switch c.Action {
+	case SyncActionUpload:
+		checksum, err := localObjectMD5(m, *c.Local)
+		if err != nil {
+			return err
+		}
+
+		eTag, err := uploadObject(client, j, *c.Local, checksum)
+		if err != nil {
+			return err
+		}
+
+		m.set(*c.Local, checksum, eTag)
+		state.set(c.Key, syncStateEntry{Size: c.Local.Size, MD5: checksum, ETag: eTag})
+
+		if err := j.recordObject(*c.Local, checksum, eTag); err != nil {
+			return err
+		}
+
+	case SyncActionDownload:
+		if err := pullAndRecordObject(client, m, j, datasetDirPath, *c.Remote); err != nil {
+			return err
+		}
+
+		checksum := c.Remote.MD5
+		if checksum == "" {
+			var err error
+			if checksum, err = fileMD5(filepath.Join(datasetDirPath, filepath.FromSlash(c.Key))); err != nil {
+				return err
+			}
+		}
+
+		state.set(c.Key, syncStateEntry{Size: c.Remote.Size, MD5: checksum, ETag: c.Remote.ETag})
+
+	case SyncActionDeleteRemote:
+		if err := client.DeleteObject(c.Key); err != nil {
+			return err
+		}
+
+		m.remove(c.Key)
+		state.remove(c.Key)
+	}
+
+	return nil
+}
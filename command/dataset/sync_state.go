@@ -0,0 +1,98 @@
+package dataset
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const syncStateFilename = "sync.json"
+
+type syncStateEntry struct {
+	Size int64  `json:"size"`
+	MD5  string `json:"md5"`
+	ETag string `json:"eTag"`
+}
+
+// syncState is the base snapshot of a dataset for "deploifai dataset sync", which records every file
+// as it was, both locally and remotely, after it was last synced.
+// Comparing each side with it tells which side a file was changed on since.
+type syncState struct {
+	Files map[string]syncStateEntry `json:"files"`
+
+	path  string
+	mutex sync.Mutex
+}
+
+// loadSyncState loads the base snapshot of a dataset directory.
+// If it is missing, an empty snapshot is returned, so every file that differs between the two sides is a conflict.
+func loadSyncState(datasetDirPath string) (*syncState, error) {
+
+	s := &syncState{
+		Files: map[string]syncStateEntry{},
+		path:  filepath.Join(datasetDirPath, localStateDirName, syncStateFilename),
+	}
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, s); err != nil || s.Files == nil {
+		return nil, errors.New(fmt.Sprintf("the sync state of the dataset is corrupt, delete %s to sync from scratch", s.path))
+	}
+
+	return s, nil
+}
+
+func (s *syncState) save() error {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return writeStateFile(s.path, s)
+}
+
+func (s *syncState) get(key string) (entry syncStateEntry, ok bool) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entry, ok = s.Files[key]
+
+	return entry, ok
+}
+
+func (s *syncState) keys() []string {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var keys []string
+	for key := range s.Files {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+func (s *syncState) set(key string, entry syncStateEntry) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.Files[key] = entry
+}
+
+func (s *syncState) remove(key string) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.Files, key)
+}
@@ -0,0 +1,135 @@
+package dataset
+
+import (
+	"github.com/deploifai/cli-go/command/dataset/data_storage"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGetSyncAction(t *testing.T) {
+	dir := t.TempDir()
+	modTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	local := func(content string) *localObject {
+		absPath := filepath.Join(dir, content+".txt")
+		writeTestFile(t, absPath, content)
+		return &localObject{Key: "a.txt", AbsPath: absPath, Size: int64(len(content)), ModTime: modTime}
+	}
+	remote := func(content string) *data_storage.Object {
+		return &data_storage.Object{Key: "a.txt", Size: int64(len(content)), MD5: testMD5(content), ETag: testMD5(content)}
+	}
+	base := map[string]syncStateEntry{"a.txt": {Size: 4, MD5: testMD5("base"), ETag: testMD5("base")}}
+
+	tests := []struct {
+		name   string
+		prefer string
+		base   map[string]syncStateEntry
+		local  *localObject
+		remote *data_storage.Object
+		action syncAction
+	}{
+		{name: "same on both sides", base: base, local: local("same"), remote: remote("same"), action: SyncActionNone},
+		{name: "same without a base", local: local("same"), remote: remote("same"), action: SyncActionNone},
+		{name: "changed locally", base: base, local: local("new1"), remote: remote("base"), action: SyncActionUpload},
+		{name: "changed remotely", base: base, local: local("base"), remote: remote("new2"), action: SyncActionDownload},
+		{name: "added locally", local: local("new1"), action: SyncActionUpload},
+		{name: "added remotely", remote: remote("new2"), action: SyncActionDownload},
+		{name: "deleted locally", base: base, remote: remote("base"), action: SyncActionDeleteRemote},
+		{name: "deleted remotely", base: base, local: local("base"), action: SyncActionDeleteLocal},
+		{name: "deleted on both sides", base: base, action: SyncActionNone},
+		{name: "changed on both sides", base: base, local: local("new1"), remote: remote("new2"), action: SyncActionConflict},
+		{name: "different without a base", local: local("new1"), remote: remote("new2"), action: SyncActionConflict},
+		{name: "changed on both sides, prefer local", prefer: "local", base: base, local: local("new1"), remote: remote("new2"), action: SyncActionUpload},
+		{name: "changed on both sides, prefer remote", prefer: "remote", base: base, local: local("new1"), remote: remote("new2"), action: SyncActionDownload},
+		{name: "deleted locally and changed remotely, prefer local", prefer: "local", base: base, remote: remote("new2"), action: SyncActionDeleteRemote},
+		{name: "changed locally and deleted remotely, prefer remote", prefer: "remote", base: base, local: local("new1"), action: SyncActionDeleteLocal},
+	}
+
+	defer func(prefer string) {
+		syncPrefer = prefer
+	}(syncPrefer)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			syncPrefer = tt.prefer
+
+			files := tt.base
+			if files == nil {
+				files = map[string]syncStateEntry{}
+			}
+
+			m := &manifest{Files: map[string]manifestEntry{}}
+			action, err := getSyncAction(m, &syncState{Files: files}, syncChange{Key: "a.txt", Local: tt.local, Remote: tt.remote})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if action != tt.action {
+				t.Errorf("getSyncAction() = %q, want %q", action, tt.action)
+			}
+		})
+	}
+}
+
+func TestApplySyncChange(t *testing.T) {
+	modTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, action := range []syncAction{SyncActionUpload, SyncActionDownload} {
+		t.Run(string(action), func(t *testing.T) {
+			datasetDirPath := t.TempDir()
+			absPath := filepath.Join(datasetDirPath, "a.txt")
+			client := newFakeClient()
+
+			c := syncChange{Key: "a.txt", Action: action}
+			if action == SyncActionUpload {
+				writeTestFile(t, absPath, "hello")
+				c.Local = &localObject{Key: "a.txt", AbsPath: absPath, Size: 5, ModTime: modTime}
+			} else {
+				client.objects["a.txt"] = []byte("hello")
+				c.Remote = &data_storage.Object{Key: "a.txt", Size: 5, MD5: testMD5("hello"), ETag: `"` + testMD5("hello") + `"`}
+			}
+
+			m := &manifest{Files: map[string]manifestEntry{}}
+			state, err := loadSyncState(datasetDirPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			j, err := loadJournal(datasetDirPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := j.start("sync", nil); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := applySyncChange(client, m, j, state, datasetDirPath, c); err != nil {
+				t.Fatal(err)
+			}
+
+			if string(client.objects["a.txt"]) != "hello" {
+				t.Errorf("the remote object is %q, want %q", client.objects["a.txt"], "hello")
+			}
+			if content, err := os.ReadFile(absPath); err != nil || string(content) != "hello" {
+				t.Errorf("the local file is %q, %v, want %q", content, err, "hello")
+			}
+
+			if entry, ok := state.get("a.txt"); !ok || entry.Size != 5 || entry.MD5 != testMD5("hello") {
+				t.Errorf("the base snapshot has %+v, %v, want the size and checksum of the file", entry, ok)
+			}
+			if recorded, ok := m.recorded("a.txt"); !ok || recorded.MD5 != testMD5("hello") {
+				t.Errorf("the manifest has %+v, %v, want the checksum of the file", recorded, ok)
+			}
+
+			// a synced file is logged so that an interrupted sync does not transfer it again
+			j.close()
+			logged, err := loadJournal(datasetDirPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := logged.objects["a.txt"]; !ok {
+				t.Error("the file is not logged in the journal")
+			}
+		})
+	}
+}
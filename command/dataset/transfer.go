@@ -0,0 +1,157 @@
+package dataset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/deploifai/cli-go/command/command_config/root_config"
+	"github.com/deploifai/cli-go/command/dataset/data_storage"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// transferContext is the context of the running command, which is cancelled when the command is interrupted,
+// e.g. with Ctrl-C, to stop transfers.
+var transferContext = context.Background()
+
+// transferRetries is the number of times a file is retried after it failed, waiting twice as long before every retry.
+const transferRetries = 3
+
+// transferRetryDelay is how long to wait before the first retry of a file.
+var transferRetryDelay = time.Second
+
+// transferOptions are the options of the transfers of a command.
+type transferOptions struct {
+	// Concurrency is the number of files that are transferred at the same time.
+	Concurrency int
+	// Retries is the number of times a file is retried after it failed.
+	Retries int
+	// ContinueOnError is true if a transfer carries on with the other files after a file failed for good,
+	// instead of stopping.
+	ContinueOnError bool
+	// Traffic counts the bytes that are transferred, for the progress, and limits their bandwidth.
+	// The storage client of the transfers has to be created with it.
+	Traffic *data_storage.Traffic
+}
+
+// bandwidthUnits are the units --max-bandwidth accepts, longest first so that suffixes match correctly.
+var bandwidthUnits = []struct {
+	suffix string
+	size   float64
+}{
+	{"kib", 1 << 10}, {"mib", 1 << 20}, {"gib", 1 << 30},
+	{"kb", 1e3}, {"mb", 1e6}, {"gb", 1e9},
+	{"k", 1e3}, {"m", 1e6}, {"g", 1e9},
+	{"b", 1},
+}
+
+// newTransferOptions resolves --concurrency and --max-bandwidth, falling back to the defaults in the root config.
+// Commands without these flags pass 0 and "" to use the defaults.
+func newTransferOptions(config root_config.Transfer, concurrency int, maxBandwidth string, continueOnError bool) (transferOptions, error) {
+
+	opts := transferOptions{Concurrency: runtime.NumCPU(), Retries: transferRetries, ContinueOnError: continueOnError}
+
+	if concurrency == 0 {
+		concurrency = config.Concurrency
+	}
+	if concurrency < 0 {
+		return opts, errors.New(fmt.Sprintf("invalid concurrency: %d, it must be at least 1", concurrency))
+	} else if concurrency > 0 {
+		opts.Concurrency = concurrency
+	}
+
+	if maxBandwidth == "" {
+		maxBandwidth = config.MaxBandwidth
+	}
+	var bytesPerSecond int64
+	if maxBandwidth != "" {
+		var err error
+		if bytesPerSecond, err = parseBandwidth(maxBandwidth); err != nil {
+			return opts, err
+		}
+	}
+
+	opts.Traffic = data_storage.NewTraffic(bytesPerSecond)
+
+	return opts, nil
+}
+
+// transferFailure is a file that failed for good in a transfer.
+type transferFailure struct {
+	Name string
+	Err  error
+}
+
+// transferError is returned when files of a transfer failed, after they were retried,
+// or when the transfer was interrupted before all files were transferred.
+type transferError struct {
+	Description string
+	Failures    []transferFailure
+	Completed   int
+	Total       int
+	Interrupted bool
+}
+
+func (e *transferError) Error() string {
+	if e.Interrupted {
+		return fmt.Sprintf("%s: interrupted after %d of %d files", e.Description, e.Completed, e.Total)
+	}
+	return fmt.Sprintf("%s: %d of %d files failed", e.Description, len(e.Failures), e.Total)
+}
+
+// ExitCode is 130 if the transfer was interrupted, as for SIGINT,
+// otherwise 2 if some files were transferred, as a partial success, or 1 if none were.
+func (e *transferError) ExitCode() int {
+	if e.Interrupted {
+		return 130
+	}
+	if e.Completed > 0 {
+		return 2
+	}
+	return 1
+}
+
+// retryTask runs task, and runs it again up to opts.Retries times while it fails, waiting longer every time.
+// It does not retry once transferContext is cancelled.
+func retryTask(opts transferOptions, task func() error) (err error) {
+
+	delay := transferRetryDelay
+
+	for attempt := 0; ; attempt++ {
+		if err = task(); err == nil || attempt == opts.Retries || transferContext.Err() != nil {
+			return err
+		}
+
+		select {
+		case <-transferContext.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// parseBandwidth parses a bandwidth in bytes per second, such as "50MB/s", "1.5MiB/s" or "800k".
+// Decimal units are powers of 1000, and binary units, such as MiB, are powers of 1024.
+func parseBandwidth(s string) (int64, error) {
+
+	value := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), "/s")
+
+	multiplier := 1.0
+	for _, unit := range bandwidthUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n*multiplier < 1 {
+		return 0, errors.New(fmt.Sprintf("invalid bandwidth: %s, use a number of bytes per second such as 50MB/s", s))
+	}
+
+	return int64(n * multiplier), nil
+}
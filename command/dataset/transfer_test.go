@@ -0,0 +1,131 @@
+package dataset
+
+import (
+	"context"
+	"errors"
+	"github.com/deploifai/cli-go/command/command_config/root_config"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestParseBandwidth(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    int64
+		wantErr bool
+	}{
+		{s: "800", want: 800},
+		{s: "800b", want: 800},
+		{s: "800k", want: 800 * 1000},
+		{s: "50MB/s", want: 50 * 1000 * 1000},
+		{s: " 50 mb/s ", want: 50 * 1000 * 1000},
+		{s: "1.5MiB/s", want: 3 * 512 * 1024},
+		{s: "2KiB", want: 2 * 1024},
+		{s: "1GB", want: 1000 * 1000 * 1000},
+		{s: "1GiB/s", want: 1024 * 1024 * 1024},
+		{s: "", wantErr: true},
+		{s: "MB/s", wantErr: true},
+		{s: "fast", wantErr: true},
+		{s: "0", wantErr: true},
+		{s: "-5MB", wantErr: true},
+		{s: "0.5", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseBandwidth(tt.s)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseBandwidth(%q) returned error %v, want error %v", tt.s, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseBandwidth(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestNewTransferOptions(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       root_config.Transfer
+		concurrency  int
+		maxBandwidth string
+		want         int
+		wantErr      bool
+	}{
+		{name: "defaults", want: runtime.NumCPU()},
+		{name: "from the config", config: root_config.Transfer{Concurrency: 3, MaxBandwidth: "1MB/s"}, want: 3},
+		{name: "flags override the config", config: root_config.Transfer{Concurrency: 3}, concurrency: 5, maxBandwidth: "1MB/s", want: 5},
+		{name: "invalid concurrency", concurrency: -1, wantErr: true},
+		{name: "invalid bandwidth in the config", config: root_config.Transfer{MaxBandwidth: "fast"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := newTransferOptions(tt.config, tt.concurrency, tt.maxBandwidth, false)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newTransferOptions() returned error %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if opts.Concurrency != tt.want {
+				t.Errorf("Concurrency = %d, want %d", opts.Concurrency, tt.want)
+			}
+			if opts.Retries != transferRetries {
+				t.Errorf("Retries = %d, want %d", opts.Retries, transferRetries)
+			}
+			if opts.Traffic == nil {
+				t.Fatal("Traffic is nil")
+			}
+		})
+	}
+}
+
+func TestRetryTask(t *testing.T) {
+	defer func(delay time.Duration, ctx context.Context) {
+		transferRetryDelay, transferContext = delay, ctx
+	}(transferRetryDelay, transferContext)
+
+	transferRetryDelay = time.Millisecond
+	opts := transferOptions{Retries: 3}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		failures int
+		calls    int
+		wantErr  bool
+	}{
+		{name: "succeeds at once", ctx: context.Background(), failures: 0, calls: 1},
+		{name: "succeeds after retries", ctx: context.Background(), failures: 3, calls: 4},
+		{name: "fails after all retries", ctx: context.Background(), failures: 10, calls: 4, wantErr: true},
+		{name: "no retries once cancelled", ctx: cancelled, failures: 10, calls: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transferContext = tt.ctx
+
+			calls := 0
+			err := retryTask(opts, func() error {
+				calls++
+				if calls <= tt.failures {
+					return errors.New("failed")
+				}
+				return nil
+			})
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("retryTask() returned error %v, want error %v", err, tt.wantErr)
+			}
+			if calls != tt.calls {
+				t.Errorf("retryTask() ran the task %d times, want %d", calls, tt.calls)
+			}
+		})
+	}
+}
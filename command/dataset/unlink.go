@@ -0,0 +1,69 @@
+/*
+Copyright © 2023 Sean Chok
+*/
+package dataset
+
+import (
+	"errors"
+	"github.com/deploifai/cli-go/command/command_config/project_config"
+	"github.com/deploifai/cli-go/command/ctx"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+)
+
+// unlinkCmd represents the unlink command
+var unlinkCmd = &cobra.Command{
+	Use:   "unlink",
+	Short: "Stop using a directory as a dataset",
+	Long: `Remove the dataset that contains the current directory, or the one chosen with --dataset, from the project config.
+
+The local files and the files in the dataset are kept, only the local state of the dataset in its directory is deleted.
+The directory can be initialised as a dataset again with "deploifai dataset init".
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		_context := ctx.GetContextValue(cmd)
+
+		var ds project_config.Dataset
+		var datasetDirPath string
+		var err error
+
+		// a dataset whose directory no longer exists can only be chosen with --dataset
+		if datasetFlag != "" {
+			if ds, datasetDirPath, err = getFlagDataset(cmd); err != nil {
+				return err
+			}
+		} else {
+			ok := false
+			if ok, ds, datasetDirPath, err = getDataset(*_context.Project); err != nil {
+				return err
+			} else if !ok {
+				return errors.New("the current directory is not initialised as a dataset, use --dataset to choose one")
+			}
+		}
+
+		if err = os.RemoveAll(filepath.Join(datasetDirPath, localStateDirName)); err != nil {
+			return err
+		}
+
+		delete(_context.Project.Datasets, ds.ID)
+
+		cmd.Printf("Unlinked %s from dataset %s\n", ds.LocalDirectory, ds.ID)
+
+		return nil
+	},
+}
+
+func init() {
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// unlinkCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// unlinkCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
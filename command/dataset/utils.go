@@ -1,37 +1,187 @@
 package dataset
 
 import (
+	"errors"
 	"fmt"
 	"github.com/deploifai/cli-go/command/command_config/project_config"
+	"github.com/deploifai/cli-go/command/ctx"
 	"github.com/deploifai/cli-go/utils/spinner_utils"
-	"github.com/schollz/progressbar/v3"
+	"github.com/spf13/cobra"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// getTargetDataset returns the dataset chosen with --dataset, or otherwise the dataset that contains the current working directory.
+func getTargetDataset(cmd *cobra.Command) (ok bool, dataset project_config.Dataset, dirPath string, err error) {
+
+	_context := ctx.GetContextValue(cmd)
+
+	if datasetFlag == "" {
+		return getDataset(*_context.Project)
+	}
+
+	dataset, dirPath, err = getFlagDataset(cmd)
+	if err != nil {
+		return false, dataset, dirPath, err
+	}
+
+	if _, err = os.Stat(dirPath); os.IsNotExist(err) {
+		return false, dataset, dirPath, errors.New(fmt.Sprintf("the directory %s of dataset %s no longer exists, use 'deploifai dataset relink' to move it, or 'deploifai dataset unlink' to remove it", dataset.LocalDirectory, datasetFlag))
+	} else if err != nil {
+		return false, dataset, dirPath, err
+	}
+
+	return true, dataset, dirPath, nil
+}
+
+// getFlagDataset returns the dataset chosen with --dataset, whether its directory exists or not.
+func getFlagDataset(cmd *cobra.Command) (dataset project_config.Dataset, dirPath string, err error) {
+
+	_context := ctx.GetContextValue(cmd)
+
+	if err = verifyLocalDirectories(*_context.Project); err != nil {
+		return dataset, dirPath, err
+	}
+
+	projectDir := filepath.Dir(_context.Project.ConfigFile)
+
+	// an id is resolved from the config, a name needs the API
+	if d, ok := _context.Project.Datasets[datasetFlag]; ok {
+		return d, filepath.Join(projectDir, filepath.FromSlash(d.LocalDirectory)), nil
+	}
+
+	if !_context.Project.Project.IsInitialized() {
+		return dataset, dirPath, project_config.ProjectNotInitializedError{}
+	}
+
+	dataStorage, err := findProjectDataStorage(cmd, datasetFlag)
+	if err != nil {
+		return dataset, dirPath, err
+	}
+
+	d, ok := _context.Project.Datasets[dataStorage.GetID()]
+	if !ok {
+		return dataset, dirPath, errors.New(fmt.Sprintf("dataset %s is not initialised in this project, run 'deploifai dataset init --name %s' in the directory to use for it", datasetFlag, datasetFlag))
+	}
+
+	return d, filepath.Join(projectDir, filepath.FromSlash(d.LocalDirectory)), nil
+}
+
+// getDataset returns the dataset whose directory contains the current working directory.
+// If dataset directories are nested, the innermost one is returned.
 func getDataset(projectConfig project_config.Config) (ok bool, dataset project_config.Dataset, dirPath string, err error) {
 
+	if err = verifyLocalDirectories(projectConfig); err != nil {
+		return false, dataset, dirPath, err
+	}
+
 	cwd, err := os.Getwd()
 	if err != nil {
 		return false, dataset, dirPath, err
 	}
 
+	if err = warnStaleDatasets(projectConfig); err != nil {
+		return false, dataset, dirPath, err
+	}
+
 	projectDir := filepath.Dir(projectConfig.ConfigFile)
 
 	for _, d := range projectConfig.Datasets {
 		datasetDirPath := filepath.Join(projectDir, filepath.FromSlash(d.LocalDirectory))
 
-		ok, err := isSubDir(datasetDirPath, cwd)
+		inDir, err := isSubDir(datasetDirPath, cwd)
 		if err != nil {
 			return false, dataset, dirPath, err
-		} else if ok {
-			return true, d, datasetDirPath, nil
+		}
+
+		// directories are unique, so the longest match is the innermost one whatever the order of the map
+		if inDir && len(datasetDirPath) > len(dirPath) {
+			ok, dataset, dirPath = true, d, datasetDirPath
 		}
 	}
 
-	return false, dataset, dirPath, nil
+	return ok, dataset, dirPath, nil
+}
+
+// getDatasetDirs returns the absolute paths of the directories of the datasets in the project config.
+func getDatasetDirs(projectConfig project_config.Config) map[string]bool {
+
+	projectDir := filepath.Dir(projectConfig.ConfigFile)
+
+	dirs := map[string]bool{}
+	for _, d := range projectConfig.Datasets {
+		dirs[filepath.Join(projectDir, filepath.FromSlash(d.LocalDirectory))] = true
+	}
+
+	return dirs
+}
+
+// getStaleDatasets returns the datasets whose directory no longer exists, sorted by id.
+func getStaleDatasets(projectConfig project_config.Config) ([]project_config.Dataset, error) {
+
+	projectDir := filepath.Dir(projectConfig.ConfigFile)
+
+	var stale []project_config.Dataset
+
+	for _, d := range projectConfig.Datasets {
+		if _, err := os.Stat(filepath.Join(projectDir, filepath.FromSlash(d.LocalDirectory))); os.IsNotExist(err) {
+			stale = append(stale, d)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(stale, func(i, j int) bool {
+		return stale[i].ID < stale[j].ID
+	})
+
+	return stale, nil
+}
+
+// warnStaleDatasets prints a warning for each dataset whose directory no longer exists, e.g. after it was renamed.
+// The warnings go to stderr to keep them out of output that is meant to be parsed.
+func warnStaleDatasets(projectConfig project_config.Config) error {
+
+	stale, err := getStaleDatasets(projectConfig)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range stale {
+		_, _ = fmt.Fprintf(os.Stderr, "Warning: the directory %s of dataset %s no longer exists, use 'deploifai dataset relink --dataset %s <dir>' to move it, or 'deploifai dataset unlink --dataset %s' to remove it\n",
+			d.LocalDirectory, d.ID, d.ID, d.ID)
+	}
+
+	return nil
+}
+
+// verifyLocalDirectories returns an error if two datasets in the project config use the same directory.
+func verifyLocalDirectories(projectConfig project_config.Config) error {
+
+	ids := make([]string, 0, len(projectConfig.Datasets))
+	for id := range projectConfig.Datasets {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	dirs := map[string]string{}
+
+	for _, id := range ids {
+		dir := path.Clean(projectConfig.Datasets[id].LocalDirectory)
+
+		if other, ok := dirs[dir]; ok {
+			return errors.New(fmt.Sprintf("datasets %s and %s both use the directory %s, remove one of them from %s", other, id, dir, project_config.ConfigFilename))
+		}
+		dirs[dir] = id
+	}
+
+	return nil
 }
 
 func isSubDir(parent string, child string) (bool, error) {
@@ -47,12 +197,19 @@ func isSubDir(parent string, child string) (bool, error) {
 
 }
 
-func getAbsPaths(relativePaths []string) ([]string, error) {
+// getAbsPaths converts relativePaths to absolute paths. If there are none, the current directory is used,
+// or the dataset directory if the current directory is outside of it, as it can be with --dataset.
+func getAbsPaths(datasetDirPath string, relativePaths []string) ([]string, error) {
 	if len(relativePaths) == 0 {
 		currentWorkingDirectory, err := os.Getwd()
 		if err != nil {
 			return nil, err
 		}
+		if ok, err := isSubDir(datasetDirPath, currentWorkingDirectory); err != nil {
+			return nil, err
+		} else if !ok {
+			return []string{datasetDirPath}, nil
+		}
 		return []string{currentWorkingDirectory}, nil
 	} else {
 		return convertToAbsPaths(relativePaths)
@@ -83,75 +240,169 @@ func getRemoteObjectPrefixes(datasetDirPath string, absPaths []string) (remoteOb
 	return remoteObjectPrefix, nil
 }
 
-func runDir(f func(fileCountChan chan<- int, resultChan chan<- interface{}) error, progressBarDescription string) error {
+// toSlashAll converts paths to use slashes as separators.
+func toSlashAll(paths []string) []string {
 
-	fileCountChan := make(chan int, 1)
-	resultChan := make(chan interface{})
-	errChan := make(chan error, 2)
-	defer close(fileCountChan)
-	defer close(resultChan)
-	defer close(errChan)
+	slashed := make([]string, len(paths))
+	for i, p := range paths {
+		slashed[i] = filepath.ToSlash(p)
+	}
 
-	var wg sync.WaitGroup
-	wg.Add(1)
+	return slashed
+}
 
-	go func(errChan chan<- error) {
-		defer wg.Done()
-		if err := f(fileCountChan, resultChan); err != nil {
-			errChan <- err
-		}
-	}(errChan)
-
-	go func(fileCountChan <-chan int, resultChan <-chan interface{}, errChan chan<- error) {
-
-		totalCount := <-fileCountChan
-		currentCount := 0
-		bar := progressbar.NewOptions(totalCount,
-			progressbar.OptionSetDescription(progressBarDescription),
-			progressbar.OptionFullWidth(),
-			progressbar.OptionShowCount(),
-		)
-		defer fmt.Printf("\n")
-
-		// add 0 to start the progress bar
-		if err := bar.Add(0); err != nil {
-			errChan <- err
-			return
-		}
+// displayPath returns absPath relative to the current working directory, for printing.
+func displayPath(absPath string) string {
 
-		for range resultChan {
-			currentCount++
-			if err := bar.Add(1); err != nil {
-				errChan <- err
-				return
-			}
-			if currentCount == totalCount {
-				break
+	cwd, err := os.Getwd()
+	if err != nil {
+		return absPath
+	}
+
+	rel, err := filepath.Rel(cwd, absPath)
+	if err != nil {
+		return absPath
+	}
+
+	return rel
+}
+
+// formatBytes formats a number of bytes with a binary unit, e.g. 1.5 MiB.
+func formatBytes(n int64) string {
+
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// runDir runs task for every file with a pool of workers, reporting the progress of the files in bytes.
+// A file that fails is retried with retryTask. After a file failed for good, the files that were not started yet are
+// left out, unless opts.ContinueOnError is true. The files that failed are listed at the end, and returned in a *transferError.
+// If transferContext is cancelled, no more files are started, and the files in flight are waited for before returning.
+func runDir(opts transferOptions, description string, files []transferFile, task func(i int) error) error {
+
+	p := newProgress(description, opts.Traffic, files)
+
+	indexChan := make(chan int)
+
+	var failures []transferFailure
+	var failuresMutex sync.Mutex
+	var stopped atomic.Bool
+
+	var wg sync.WaitGroup
+
+	for w := 0; w < opts.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexChan {
+				start := time.Now()
+				p.startFile(files[i])
+
+				err := retryTask(opts, func() error {
+					return task(i)
+				})
+				if err == nil {
+					p.finishFile(files[i], time.Since(start))
+					continue
+				}
+
+				failuresMutex.Lock()
+				failures = append(failures, transferFailure{Name: files[i].Name, Err: err})
+				failuresMutex.Unlock()
+
+				if !opts.ContinueOnError {
+					stopped.Store(true)
+				}
 			}
+		}()
+	}
+
+	started := 0
+dispatch:
+	for i := range files {
+		if stopped.Load() {
+			break
+		}
+		select {
+		case indexChan <- i:
+			started++
+		case <-transferContext.Done():
+			break dispatch
 		}
-	}(fileCountChan, resultChan, errChan)
+	}
+	close(indexChan)
 
+	// let the files in flight finish or fail, before reporting what was transferred
 	wg.Wait()
+	p.finish()
 
-	select {
-	case err := <-errChan:
-		return err
-	default: // do nothing
+	if transferContext.Err() != nil {
+		completed := started - len(failures)
+		fmt.Printf("Interrupted, %d of %d files finished, the others were left out\n", completed, len(files))
+
+		return &transferError{
+			Description: description,
+			Completed:   completed,
+			Total:       len(files),
+			Interrupted: true,
+		}
+	}
+
+	if len(failures) == 0 {
 		return nil
 	}
 
+	sort.Slice(failures, func(i, j int) bool {
+		return failures[i].Name < failures[j].Name
+	})
+
+	fmt.Printf("%d of %d files failed:\n", len(failures), len(files))
+	for _, f := range failures {
+		fmt.Printf("  %s: %s\n", f.Name, f.Err)
+	}
+	if notStarted := len(files) - started; notStarted > 0 {
+		fmt.Printf("%d files were left out after the first failure, use --continue-on-error to carry on after a failure\n", notStarted)
+	}
+
+	return &transferError{
+		Description: description,
+		Failures:    failures,
+		Completed:   started - len(failures),
+		Total:       len(files),
+	}
 }
 
-func runFile(f func() error, prefixMessage string, finalMessage string) error {
+// runFile runs f for a single file, retrying it with retryTask, and shows a spinner while it runs.
+func runFile(opts transferOptions, f func() error, prefixMessage string, finalMessage string) error {
+
+	// a spinner would fill logs with escape codes
+	if !isTerminal() {
+		fmt.Println(strings.TrimSpace(prefixMessage))
+		if err := retryTask(opts, f); err != nil {
+			return interruptedFileError(prefixMessage, err)
+		}
+		fmt.Println(finalMessage)
+		return nil
+	}
 
 	spinner := spinner_utils.NewAPICallSpinner()
 	spinner.Prefix = prefixMessage
 
 	spinner.Start()
 
-	if err := f(); err != nil {
+	if err := retryTask(opts, f); err != nil {
 		spinner.Stop()
-		return err
+		return interruptedFileError(prefixMessage, err)
 	}
 
 	spinner.Stop()
@@ -159,3 +410,12 @@ func runFile(f func() error, prefixMessage string, finalMessage string) error {
 
 	return nil
 }
+
+// interruptedFileError returns a *transferError for the single file of runFile if transferContext was cancelled,
+// or otherwise err.
+func interruptedFileError(prefixMessage string, err error) error {
+	if transferContext.Err() == nil {
+		return err
+	}
+	return &transferError{Description: strings.TrimSpace(prefixMessage), Total: 1, Interrupted: true}
+}
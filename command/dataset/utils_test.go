@@ -0,0 +1,72 @@
+package dataset
+
+import (
+	"github.com/deploifai/cli-go/command/command_config/project_config"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetDataset(t *testing.T) {
+	projectDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	projectConfig := project_config.Config{
+		ConfigFile: filepath.Join(projectDir, project_config.ConfigFilename),
+		Datasets: project_config.Datasets{
+			"outer": {ID: "outer", LocalDirectory: "data"},
+			"inner": {ID: "inner", LocalDirectory: "data/sub"},
+			"deep":  {ID: "deep", LocalDirectory: "data/sub/deeper/deepest"},
+			"other": {ID: "other", LocalDirectory: "data-other"},
+		},
+	}
+
+	for _, dir := range []string{"data/sub/deeper/deepest", "data/sub/x", "data/y", "data-other"} {
+		if err := os.MkdirAll(filepath.Join(projectDir, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		cwd string
+		ok  bool
+		id  string
+	}{
+		{cwd: ".", ok: false},
+		{cwd: "data", ok: true, id: "outer"},
+		{cwd: "data/y", ok: true, id: "outer"},
+		{cwd: "data/sub", ok: true, id: "inner"},
+		{cwd: "data/sub/x", ok: true, id: "inner"},
+		{cwd: "data/sub/deeper", ok: true, id: "inner"},
+		{cwd: "data/sub/deeper/deepest", ok: true, id: "deep"},
+		// a directory whose name starts with the name of a dataset directory is not in it
+		{cwd: "data-other", ok: true, id: "other"},
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.Chdir(wd)
+	}()
+
+	for _, tt := range tests {
+		if err := os.Chdir(filepath.Join(projectDir, tt.cwd)); err != nil {
+			t.Fatal(err)
+		}
+
+		ok, d, dirPath, err := getDataset(projectConfig)
+		if err != nil {
+			t.Fatalf("getDataset() in %s returned an error: %v", tt.cwd, err)
+		}
+		if ok != tt.ok || d.ID != tt.id {
+			t.Errorf("getDataset() in %s = %v, %q, want %v, %q", tt.cwd, ok, d.ID, tt.ok, tt.id)
+		}
+		if ok && dirPath != filepath.Join(projectDir, filepath.FromSlash(projectConfig.Datasets[tt.id].LocalDirectory)) {
+			t.Errorf("getDataset() in %s returned the directory %s of dataset %s", tt.cwd, dirPath, tt.id)
+		}
+	}
+}
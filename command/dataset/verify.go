@@ -0,0 +1,250 @@
+/*
+Copyright © 2023 Sean Chok
+*/
+package dataset
+
+import (
+	"errors"
+	"fmt"
+	"github.com/deploifai/cli-go/command/ctx"
+	"github.com/deploifai/cli-go/command/dataset/data_storage"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var verifyRepair bool
+
+type verifyResult string
+
+const (
+	VerifyResultOK         verifyResult = "ok"
+	VerifyResultMissing    verifyResult = "missing"
+	VerifyResultTruncated  verifyResult = "truncated"
+	VerifyResultMismatched verifyResult = "mismatched"
+)
+
+type verifyEntry struct {
+	Remote    data_storage.Object
+	Result    verifyResult
+	LocalSize int64
+
+	// SizeOnly is true if the cloud provider reports no checksum for the object, so only its size was compared
+	SizeOnly bool
+}
+
+// verifyCmd represents the verify command
+var verifyCmd = &cobra.Command{
+	Use:   "verify [<path>...]",
+	Short: "Verify local files against the checksums of a dataset",
+	Long: `Verify that the local files are intact copies of the objects in a dataset,
+by comparing their sizes, and the checksums of their content with the checksums the cloud provider reports.
+
+Unlike "deploifai dataset status", every file is read and checksummed, so corruption that does not change
+the modification time of a file, e.g. a download that was interrupted, is found too.
+Objects that the cloud provider reports no checksum for, e.g. files uploaded in parts, are only compared by size.
+
+A file is "missing" if it does not exist locally, "truncated" if it is smaller than the object,
+and "mismatched" if it is larger than the object or its content differs. Local files that are not in the dataset are not checked.
+The command fails if any file is missing, truncated or mismatched.
+Use --repair to download those files again.
+
+Each <path> can be a directory or a file.
+If no <path> is specified, the current directory is used.
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		_context := ctx.GetContextValue(cmd)
+
+		// get the dataset and directory path from config
+		ok, ds, datasetDirPath, err := getTargetDataset(cmd)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("the current directory is not initialised as a dataset, use --dataset to choose one")
+		}
+
+		absPaths, err := getAbsPaths(datasetDirPath, args)
+		if err != nil {
+			return err
+		}
+
+		// verify the paths, which do not have to exist locally
+		if ok, invalidArgs, err := verifyPullPaths(datasetDirPath, args, absPaths); err != nil {
+			return err
+		} else if !ok {
+			return errors.New(fmt.Sprintf("invalid paths: %s", strings.Join(invalidArgs, ", ")))
+		}
+
+		remoteObjectPrefixes, err := getRemoteObjectPrefixes(datasetDirPath, absPaths)
+		if err != nil {
+			return err
+		}
+
+		opts, err := newTransferOptions(_context.Root.Transfer, 0, "", false)
+		if err != nil {
+			return err
+		}
+
+		client, err := data_storage.New(cmd.Context(), _context.ServiceClientConfig.API, ds.ID, opts.Traffic)
+		if err != nil {
+			return err
+		}
+
+		remoteObjects := map[string]data_storage.Object{}
+		for _, prefix := range remoteObjectPrefixes {
+			objects, err := listRemoteObjects(client, prefix)
+			if err != nil {
+				return err
+			}
+			for key, r := range objects {
+				remoteObjects[key] = r
+			}
+		}
+
+		entries, err := verifyObjects(opts, datasetDirPath, remoteObjects)
+		if err != nil {
+			return err
+		}
+
+		var bad []data_storage.Object
+		sizeOnly := 0
+		for _, e := range entries {
+			if e.SizeOnly {
+				sizeOnly++
+			}
+			switch e.Result {
+			case VerifyResultOK:
+				continue
+			case VerifyResultMissing:
+				cmd.Printf("  %-10s  %s\n", e.Result, e.Remote.Key)
+			default:
+				cmd.Printf("  %-10s  %s (%s locally, %s in the dataset)\n", e.Result, e.Remote.Key, formatBytes(e.LocalSize), formatBytes(e.Remote.Size))
+			}
+			bad = append(bad, e.Remote)
+		}
+
+		cmd.Printf("Verified %d files, %d failed\n", len(entries), len(bad))
+		if sizeOnly > 0 {
+			cmd.Printf("%d files have no checksum in the dataset, and were only compared by size\n", sizeOnly)
+		}
+
+		if len(bad) == 0 {
+			return nil
+		}
+
+		if !verifyRepair {
+			return errors.New(fmt.Sprintf("%d files failed verification, use --repair to download them again", len(bad)))
+		}
+
+		m, _, err := loadManifest(datasetDirPath)
+		if err != nil {
+			cmd.Printf("Warning: %s, starting a new manifest\n", err)
+		}
+
+		j, err := loadJournal(datasetDirPath)
+		if err != nil {
+			return err
+		}
+		j.replay(m)
+		if err = j.start("verify", toSlashAll(remoteObjectPrefixes)); err != nil {
+			return err
+		}
+		defer j.close()
+
+		err = pullObjects(client, opts, m, j, datasetDirPath, bad, 0, "Repairing")
+
+		// save the files that were repaired, even if some failed
+		if saveErr := m.save(); err == nil {
+			err = saveErr
+		}
+		if err != nil {
+			return err
+		}
+
+		if err = j.finish(client); err != nil {
+			return err
+		}
+
+		cmd.Printf("Repaired %d files\n", len(bad))
+
+		return nil
+	},
+}
+
+func init() {
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// verifyCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// verifyCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	verifyCmd.Flags().BoolVar(&verifyRepair, "repair", false, "download the files that failed verification again")
+}
+
+// verifyObjects compares the local file of every remote object with it, reading the whole file to compare checksums.
+// The entries are sorted by key.
+func verifyObjects(opts transferOptions, datasetDirPath string, remoteObjects map[string]data_storage.Object) ([]verifyEntry, error) {
+
+	entries := make([]verifyEntry, 0, len(remoteObjects))
+	for _, r := range remoteObjects {
+		entries = append(entries, verifyEntry{Remote: r, Result: VerifyResultOK, SizeOnly: r.MD5 == ""})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Remote.Key < entries[j].Remote.Key
+	})
+
+	// files of the right size have their checksums compared, which is what takes time
+	var toHash []int
+	var files []transferFile
+
+	for i := range entries {
+		e := &entries[i]
+
+		info, err := os.Stat(filepath.Join(datasetDirPath, filepath.FromSlash(e.Remote.Key)))
+		if os.IsNotExist(err) || (err == nil && info.IsDir()) {
+			e.Result = VerifyResultMissing
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		e.LocalSize = info.Size()
+		switch {
+		case e.LocalSize < e.Remote.Size:
+			e.Result = VerifyResultTruncated
+		case e.LocalSize > e.Remote.Size:
+			e.Result = VerifyResultMismatched
+		case !e.SizeOnly:
+			toHash = append(toHash, i)
+			files = append(files, transferFile{Name: e.Remote.Key, Size: e.LocalSize})
+		}
+	}
+
+	if len(toHash) == 0 {
+		return entries, nil
+	}
+
+	err := runDir(opts, "Verifying", files, func(i int) error {
+		e := &entries[toHash[i]]
+
+		checksum, err := fileMD5(filepath.Join(datasetDirPath, filepath.FromSlash(e.Remote.Key)))
+		if err != nil {
+			return err
+		}
+		if checksum != e.Remote.MD5 {
+			e.Result = VerifyResultMismatched
+		}
+
+		return nil
+	})
+
+	return entries, err
+}
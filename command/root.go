@@ -36,7 +36,9 @@ import (
 	"github.com/deploifai/sdk-go/credentials"
 	"golang.org/x/net/context"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -96,7 +98,8 @@ func init() {
 // initConfigs reads config files and ENV variables if set.
 func initConfigs() {
 
-	bgCtx := context.Background()
+	// the context of Execute, which is cancelled when the command is interrupted
+	bgCtx := rootCmd.Context()
 
 	// Initialize root config
 	err := initRootConfig()
@@ -258,7 +261,23 @@ func writeConfig(v *viper.Viper, dirPerm os.FileMode, filePerm os.FileMode) erro
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	err := rootCmd.Execute()
+
+	// cancel the context of the command on Ctrl-C or SIGTERM, so that it can stop cleanly
+	c, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		// a second signal kills the command right away
+		<-c.Done()
+		stop()
+	}()
+
+	err := rootCmd.ExecuteContext(c)
+	stop()
+
+	// commands can exit with another code than 1 to tell failures apart, e.g. a partial success
+	var exitCoder interface{ ExitCode() int }
+	if errors.As(err, &exitCoder) {
+		os.Exit(exitCoder.ExitCode())
+	}
 	if err != nil {
 		os.Exit(1)
 	}